@@ -3,6 +3,7 @@ package aini
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"io"
 	"os"
 	"path"
@@ -15,6 +16,29 @@ import (
 type InventoryData struct {
 	Groups map[string]*Group
 	Hosts  map[string]*Host
+
+	// Whether names are compared case-insensitively, see SetCaseInsensitive
+	caseInsensitive bool
+	// Path of the parsed inventory file, empty if not parsed from a file
+	path string
+	// Directories which group_vars and host_vars were loaded from
+	varsRoots []varsRoot
+	// Problems of vars files skipped while loading, for Lint
+	varsProblems []*VarsFileError
+	// Locations of hosts and groups in the inventory file, by original names
+	hostLines  map[string][]hostDefinition
+	groupLines map[string]int
+	// Sequence numbers of hosts and groups in order of appearance, see InventoryOrder
+	hostSequence  map[*Host]int
+	groupSequence map[*Group]int
+	// Limits of the parsing in progress by an inventory source
+	parseOptions ParseOptions
+}
+
+// hostDefinition is a host line in inventory file with inline vars
+type hostDefinition struct {
+	line int
+	vars map[string]string
 }
 
 // Group represents ansible group
@@ -48,6 +72,9 @@ type Host struct {
 	InventoryVars map[string]string
 	// Vars set in host_vars
 	FileVars map[string]string
+
+	// Whether Port was given explicitly on the host line
+	portSet bool
 }
 
 // ParseFile parses Inventory represented as a file
@@ -57,7 +84,9 @@ func ParseFile(f string) (*InventoryData, error) {
 		return &InventoryData{}, err
 	}
 
-	return Parse(bytes.NewReader(bs))
+	inventory, err := Parse(bytes.NewReader(bs))
+	inventory.path = f
+	return inventory, err
 }
 
 // ParseString parses Inventory represented as a string
@@ -67,9 +96,32 @@ func ParseString(input string) (*InventoryData, error) {
 
 // Parse using some Reader
 func Parse(r io.Reader) (*InventoryData, error) {
+	return ParseContext(context.Background(), r, ParseOptions{})
+}
+
+// ParseFileContext parses Inventory represented as a file, with limits and cancellation
+func ParseFileContext(ctx context.Context, f string, options ParseOptions) (*InventoryData, error) {
+	file, err := os.Open(f)
+	if err != nil {
+		return &InventoryData{}, err
+	}
+	defer file.Close()
+
+	inventory, err := ParseContext(ctx, file, options)
+	inventory.path = f
+	return inventory, err
+}
+
+// ParseStringContext parses Inventory represented as a string, with limits and cancellation
+func ParseStringContext(ctx context.Context, input string, options ParseOptions) (*InventoryData, error) {
+	return ParseContext(ctx, strings.NewReader(input), options)
+}
+
+// ParseContext parses Inventory from some Reader, with limits and cancellation
+func ParseContext(ctx context.Context, r io.Reader, options ParseOptions) (*InventoryData, error) {
 	input := bufio.NewReader(r)
 	inventory := &InventoryData{}
-	err := inventory.parse(input)
+	err := inventory.parse(ctx, input, options)
 	if err != nil {
 		return inventory, err
 	}
@@ -91,6 +143,12 @@ func (inventory *InventoryData) Match(pattern string) []*Host {
 
 // GroupMapListValues transforms map of Groups into Group list in lexical order
 func GroupMapListValues(mymap map[string]*Group) []*Group {
+	return GroupMapListValuesSorted(mymap, LexicalOrder)
+}
+
+// GroupMapListValuesSorted transforms map of Groups into Group list in lexical or natural order.
+// InventoryOrder is not known from maps and sorts as LexicalOrder, see GroupSet for it.
+func GroupMapListValuesSorted(mymap map[string]*Group, order SortOrder) []*Group {
 	values := make([]*Group, len(mymap))
 
 	i := 0
@@ -99,13 +157,19 @@ func GroupMapListValues(mymap map[string]*Group) []*Group {
 		i++
 	}
 	sort.Slice(values, func(i, j int) bool {
-		return values[i].Name < values[j].Name
+		return order.compareNames(values[i].Name, values[j].Name) < 0
 	})
 	return values
 }
 
 // HostMapListValues transforms map of Hosts into Host list in lexical order
 func HostMapListValues(mymap map[string]*Host) []*Host {
+	return HostMapListValuesSorted(mymap, LexicalOrder)
+}
+
+// HostMapListValuesSorted transforms map of Hosts into Host list in lexical or natural order.
+// InventoryOrder is not known from maps and sorts as LexicalOrder, see HostSet for it.
+func HostMapListValuesSorted(mymap map[string]*Host, order SortOrder) []*Host {
 	values := make([]*Host, len(mymap))
 
 	i := 0
@@ -114,7 +178,7 @@ func HostMapListValues(mymap map[string]*Host) []*Host {
 		i++
 	}
 	sort.Slice(values, func(i, j int) bool {
-		return values[i].Name < values[j].Name
+		return order.compareNames(values[i].Name, values[j].Name) < 0
 	})
 	return values
 }
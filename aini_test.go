@@ -1,8 +1,12 @@
 package aini
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -469,3 +473,78 @@ func TestGroupMapListValues(t *testing.T) {
 		assert.Contains(t, groups, v)
 	}
 }
+
+func TestMapListValuesSorted(t *testing.T) {
+	v := parseString(t, `
+	[group10]
+	host10
+	host2
+	[group9]
+	host1
+	`)
+
+	hostNames := getHostNames(HostMapListValuesSorted(v.Hosts, NaturalOrder))
+	assert.Equal(t, []string{"host1", "host2", "host10"}, hostNames)
+	hostNames = getHostNames(HostMapListValuesSorted(v.Hosts, LexicalOrder))
+	assert.Equal(t, []string{"host1", "host10", "host2"}, hostNames)
+
+	groupNames := getGroupNamesOf(GroupMapListValuesSorted(v.Groups, NaturalOrder))
+	assert.Equal(t, []string{"all", "group9", "group10", "ungrouped"}, groupNames)
+	groupNames = getGroupNamesOf(GroupMapListValuesSorted(v.Groups, InventoryOrder))
+	assert.Equal(t, []string{"all", "group10", "group9", "ungrouped"}, groupNames)
+}
+
+func getHostNames(hosts []*Host) []string {
+	names := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		names = append(names, host.Name)
+	}
+	return names
+}
+
+func TestParseLimits(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := ParseStringContext(ctx, "node[0:9999999]", ParseOptions{MaxHostsPerLine: 1000})
+	assert.True(t, errors.Is(err, ErrLimitExceeded))
+
+	_, err = ParseStringContext(ctx, "rack[1:10]-node[01:200]", ParseOptions{MaxHostsPerLine: 1000})
+	assert.True(t, errors.Is(err, ErrLimitExceeded))
+
+	v, err := ParseStringContext(ctx, "rack[1:5]-node[01:200]", ParseOptions{MaxHostsPerLine: 1000})
+	assert.Nil(t, err)
+	assert.Len(t, v.Hosts, 1000)
+
+	_, err = ParseStringContext(ctx, "node[1:600]\nnode[601:1200]\n", ParseOptions{MaxHosts: 1000})
+	assert.True(t, errors.Is(err, ErrLimitExceeded))
+	assert.Contains(t, err.Error(), "line 2")
+
+	_, err = ParseStringContext(ctx, "node[1:10:0]", ParseOptions{})
+	assert.NotNil(t, err)
+
+	longLine := "host1 var=" + strings.Repeat("x", 100)
+	_, err = ParseStringContext(ctx, "host0\n"+longLine, ParseOptions{MaxLineLength: 50})
+	assert.True(t, errors.Is(err, ErrLimitExceeded))
+	assert.Contains(t, err.Error(), "line 2")
+
+	_, err = ParseString(strings.Repeat("x", 100*1024))
+	assert.True(t, errors.Is(err, ErrLimitExceeded), "Lines over 64KB must fail instead of being skipped")
+
+	v, err = ParseStringContext(ctx, longLine, ParseOptions{MaxLineLength: 1000})
+	assert.Nil(t, err)
+	assert.Contains(t, v.Hosts, "host1")
+}
+
+func TestParseCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := ParseStringContext(ctx, "host1", ParseOptions{})
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = ParseStringContext(ctx, "node[0:9]-[0:9999999]", ParseOptions{})
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Less(t, int64(time.Since(start)), int64(5*time.Second))
+}
@@ -0,0 +1,288 @@
+package aini
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// cacheFormatVersion is part of cache keys, to be increased when parsing results may change
+const cacheFormatVersion = 2
+
+// ParseCache is an on-disk cache of inventories parsed from files with variables loaded.
+//
+// Cached inventories are reused as long as none of input files changed: the inventory file and
+// all files in group_vars and host_vars directories. A new result replaces the previous one of the same inputs.
+type ParseCache struct {
+	// Dir is the directory to store cached inventories
+	Dir string
+	// HashContents makes cache keys from contents of files, instead of sizes and modification times
+	HashContents bool
+	// OnWriteError is called if the parsed inventory cannot be stored, which doesn't fail parsing
+	OnWriteError func(entryPath string, err error)
+}
+
+type cacheEntry struct {
+	Key       string
+	Inventory *InventoryData
+	State     cachedState
+}
+
+// cachedState is the unexported state of InventoryData, which is not in its JSON form
+type cachedState struct {
+	CaseInsensitive bool
+	Path            string
+	VarsRoots       []cachedVarsRoot
+	VarsProblems    []cachedVarsProblem
+	HostLines       map[string][]cachedHostDefinition
+	GroupLines      map[string]int
+	// HostOrder and GroupOrder are names in sequence of appearance, see InventoryOrder
+	HostOrder  []string
+	GroupOrder []string
+	// PortSetHosts are names of hosts with port given on host lines
+	PortSetHosts []string
+}
+
+type cachedVarsRoot struct {
+	Path       string
+	Lowercased bool
+}
+
+type cachedVarsProblem struct {
+	Path  string
+	Line  int
+	Error string
+}
+
+type cachedHostDefinition struct {
+	Line int
+	Vars map[string]string
+}
+
+// newCachedState takes the unexported state of the inventory
+func newCachedState(inventory *InventoryData) cachedState {
+	state := cachedState{
+		CaseInsensitive: inventory.caseInsensitive,
+		Path:            inventory.path,
+		HostLines:       make(map[string][]cachedHostDefinition, len(inventory.hostLines)),
+		GroupLines:      inventory.groupLines,
+		HostOrder:       inventory.AllHosts().SortedNames(InventoryOrder),
+		GroupOrder:      inventory.AllGroups().SortedNames(InventoryOrder),
+		PortSetHosts:    make([]string, 0),
+	}
+	for _, root := range inventory.varsRoots {
+		state.VarsRoots = append(state.VarsRoots, cachedVarsRoot{Path: root.path, Lowercased: root.lowercased})
+	}
+	for _, problem := range inventory.varsProblems {
+		state.VarsProblems = append(state.VarsProblems, cachedVarsProblem{Path: problem.Path, Line: problem.Line, Error: problem.Err.Error()})
+	}
+	for name, definitions := range inventory.hostLines {
+		for _, definition := range definitions {
+			state.HostLines[name] = append(state.HostLines[name], cachedHostDefinition{Line: definition.line, Vars: definition.vars})
+		}
+	}
+	for _, name := range sortedKeys(inventory.Hosts) {
+		if inventory.Hosts[name].portSet {
+			state.PortSetHosts = append(state.PortSetHosts, name)
+		}
+	}
+	return state
+}
+
+// restore sets the unexported state of the inventory decoded from cache
+func (state cachedState) restore(inventory *InventoryData) {
+	inventory.caseInsensitive = state.CaseInsensitive
+	inventory.path = state.Path
+	inventory.varsRoots = nil
+	for _, root := range state.VarsRoots {
+		inventory.varsRoots = append(inventory.varsRoots, varsRoot{path: root.Path, lowercased: root.Lowercased})
+	}
+	inventory.varsProblems = nil
+	for _, problem := range state.VarsProblems {
+		inventory.varsProblems = append(inventory.varsProblems, &VarsFileError{Path: problem.Path, Line: problem.Line, Err: errors.New(problem.Error)})
+	}
+	inventory.hostLines = make(map[string][]hostDefinition, len(state.HostLines))
+	for name, definitions := range state.HostLines {
+		for _, definition := range definitions {
+			inventory.hostLines[name] = append(inventory.hostLines[name], hostDefinition{line: definition.Line, vars: definition.Vars})
+		}
+	}
+	inventory.groupLines = state.GroupLines
+	if inventory.groupLines == nil {
+		inventory.groupLines = make(map[string]int)
+	}
+	inventory.hostSequence = make(map[*Host]int, len(state.HostOrder))
+	for index, name := range state.HostOrder {
+		if host, ok := inventory.Hosts[name]; ok {
+			inventory.hostSequence[host] = index
+		}
+	}
+	inventory.groupSequence = make(map[*Group]int, len(state.GroupOrder))
+	for index, name := range state.GroupOrder {
+		if group, ok := inventory.Groups[name]; ok {
+			inventory.groupSequence[group] = index
+		}
+	}
+	for _, name := range state.PortSetHosts {
+		if host, ok := inventory.Hosts[name]; ok {
+			host.portSet = true
+		}
+	}
+}
+
+// NewParseCache creates ParseCache in the given directory, which is created if not exists
+func NewParseCache(dir string) (*ParseCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &ParseCache{Dir: dir}, nil
+}
+
+// ParseFile does the same as ParseFile followed by AddVars for each of varsPaths, using cached result if possible
+func (cache *ParseCache) ParseFile(path string, varsPaths ...string) (*InventoryData, error) {
+	return cache.parseFile(path, varsPaths, false)
+}
+
+// ParseFileLowerCased does the same as ParseFile followed by HostsToLower, GroupsToLower and AddVarsLowerCased
+// for each of varsPaths, using cached result if possible
+func (cache *ParseCache) ParseFileLowerCased(path string, varsPaths ...string) (*InventoryData, error) {
+	return cache.parseFile(path, varsPaths, true)
+}
+
+func (cache *ParseCache) parseFile(path string, varsPaths []string, lowercased bool) (*InventoryData, error) {
+	entryPath, key, err := cache.entryPathAndKey(path, varsPaths, lowercased)
+	if err != nil {
+		return &InventoryData{}, err
+	}
+	if inventory := cache.load(entryPath, key); inventory != nil {
+		return inventory, nil
+	}
+
+	inventory, err := ParseFile(path)
+	if err != nil {
+		return inventory, err
+	}
+	if lowercased {
+		inventory.HostsToLower()
+		inventory.GroupsToLower()
+	}
+	for _, varsPath := range varsPaths {
+		if err := inventory.doAddVars(varsPath, varsOptions{lowercased: lowercased}); err != nil {
+			return inventory, err
+		}
+	}
+
+	if err := cache.store(entryPath, key, inventory); err != nil && cache.OnWriteError != nil {
+		cache.OnWriteError(entryPath, err)
+	}
+	return inventory, nil
+}
+
+// store writes the cache entry of the inventory
+func (cache *ParseCache) store(entryPath string, key string, inventory *InventoryData) error {
+	data, err := json.Marshal(cacheEntry{Key: key, Inventory: inventory, State: newCachedState(inventory)})
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(entryPath, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write inventory cache: %w", err)
+	}
+	return nil
+}
+
+// load returns the cached inventory if present with the same key, or nil
+func (cache *ParseCache) load(entryPath string, key string) *InventoryData {
+	data, err := os.ReadFile(entryPath)
+	if err != nil {
+		return nil
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Key != key || entry.Inventory == nil {
+		return nil
+	}
+	entry.State.restore(entry.Inventory)
+	return entry.Inventory
+}
+
+// entryPathAndKey returns the cache file path for the given inputs and the key of their current state
+func (cache *ParseCache) entryPathAndKey(path string, varsPaths []string, lowercased bool) (string, string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", "", err
+	}
+	inputs := []string{absPath}
+	for _, varsPath := range varsPaths {
+		absVarsPath, err := filepath.Abs(varsPath)
+		if err != nil {
+			return "", "", err
+		}
+		inputs = append(inputs, filepath.Join(absVarsPath, "group_vars"), filepath.Join(absVarsPath, "host_vars"))
+	}
+
+	slot := sha256.New()
+	fmt.Fprintf(slot, "%d\n%t\n", cacheFormatVersion, lowercased)
+	for _, input := range inputs {
+		fmt.Fprintln(slot, input)
+	}
+	slotHex := hex.EncodeToString(slot.Sum(nil))
+
+	key := sha256.New()
+	io.WriteString(key, slotHex)
+	for _, input := range inputs {
+		if err := hashFileTree(key, input, cache.HashContents); err != nil {
+			return "", "", err
+		}
+	}
+	return filepath.Join(cache.Dir, slotHex+".json"), hex.EncodeToString(key.Sum(nil)), nil
+}
+
+// hashFileTree adds state of a file or all files in a directory tree to the hash,
+// which is either contents or sizes and modification times of files
+func hashFileTree(h hash.Hash, root string, hashContents bool) error {
+	if _, err := os.Stat(root); err != nil {
+		// missing var directories are part of the state too
+		fmt.Fprintf(h, "%s\x00missing\n", root)
+		return nil
+	}
+	paths := make([]string, 0)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		paths = append(paths, path)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			fmt.Fprintf(h, "%s\x00dir\n", path)
+			continue
+		}
+		if !hashContents {
+			fmt.Fprintf(h, "%s\x00%d\x00%d\n", path, info.Size(), info.ModTime().UnixNano())
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		fmt.Fprintf(h, "%s\x00%x\n", path, sum)
+	}
+	return nil
+}
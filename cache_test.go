@@ -0,0 +1,129 @@
+package aini
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCache(t *testing.T) {
+	for _, hashContents := range []bool{false, true} {
+		root := t.TempDir()
+		inventoryPath := filepath.Join(root, "inventory")
+		varsPath := filepath.Join(root, "group_vars", "web.yml")
+		assert.Nil(t, os.WriteFile(inventoryPath, []byte("[web]\nHost1\n"), 0o644))
+		assert.Nil(t, os.MkdirAll(filepath.Dir(varsPath), 0o755))
+		assert.Nil(t, os.WriteFile(varsPath, []byte("web_var: one\n"), 0o644))
+
+		cache, err := NewParseCache(filepath.Join(root, "cache"))
+		assert.Nil(t, err)
+		cache.HashContents = hashContents
+
+		v, err := cache.ParseFile(inventoryPath, root)
+		assert.Nil(t, err)
+		assert.Equal(t, "one", v.Hosts["Host1"].Vars["web_var"])
+
+		// tamper with the cache to verify it's used
+		entries, err := os.ReadDir(cache.Dir)
+		assert.Nil(t, err)
+		assert.Len(t, entries, 1)
+		entryPath := filepath.Join(cache.Dir, entries[0].Name())
+		data, err := os.ReadFile(entryPath)
+		assert.Nil(t, err)
+		assert.Nil(t, os.WriteFile(entryPath, []byte(strings.ReplaceAll(string(data), `"one"`, `"cached"`)), 0o644))
+
+		v, err = cache.ParseFile(inventoryPath, root)
+		assert.Nil(t, err)
+		assert.Equal(t, "cached", v.Hosts["Host1"].Vars["web_var"])
+		assert.Contains(t, v.Groups["web"].Hosts, "Host1")
+		assert.Same(t, v.Hosts["Host1"], v.Groups["web"].Hosts["Host1"])
+
+		// different options use different cache entries
+		v, err = cache.ParseFileLowerCased(inventoryPath, root)
+		assert.Nil(t, err)
+		assert.Contains(t, v.Hosts, "host1")
+
+		// changes invalidate the cache
+		assert.Nil(t, os.WriteFile(varsPath, []byte("web_var: two\n"), 0o644))
+		assert.Nil(t, os.Chtimes(varsPath, time.Now().Add(time.Minute), time.Now().Add(time.Minute)))
+		v, err = cache.ParseFile(inventoryPath, root)
+		assert.Nil(t, err)
+		assert.Equal(t, "two", v.Hosts["Host1"].Vars["web_var"])
+
+		hostVarsPath := filepath.Join(root, "host_vars", "Host1.yml")
+		assert.Nil(t, os.MkdirAll(filepath.Dir(hostVarsPath), 0o755))
+		assert.Nil(t, os.WriteFile(hostVarsPath, []byte("host_var: three\n"), 0o644))
+		v, err = cache.ParseFile(inventoryPath, root)
+		assert.Nil(t, err)
+		assert.Equal(t, "three", v.Hosts["Host1"].Vars["host_var"])
+
+		entries, err = os.ReadDir(cache.Dir)
+		assert.Nil(t, err)
+		assert.Len(t, entries, 2)
+	}
+}
+
+func TestParseCacheKeepsState(t *testing.T) {
+	root := writeVarsFiles(t, map[string]string{
+		"inventory":          "[web]\nweb2:22\nweb1 a=1\nweb1 a=2\n[db]\ndb1\n[empty]\n",
+		"group_vars/web.yml": "web_var: one\n",
+		"host_vars/gone.yml": "x: 1\n",
+	})
+	inventoryPath := filepath.Join(root, "inventory")
+	cache, err := NewParseCache(filepath.Join(root, "cache"))
+	assert.Nil(t, err)
+
+	fresh, err := cache.ParseFile(inventoryPath, root)
+	assert.Nil(t, err)
+	cached, err := cache.ParseFile(inventoryPath, root)
+	assert.Nil(t, err)
+	assert.NotSame(t, fresh, cached)
+
+	assert.Equal(t, fresh.MagicVars(fresh.Hosts["web1"]), cached.MagicVars(cached.Hosts["web1"]))
+	assert.NotEmpty(t, cached.MagicVars(cached.Hosts["web1"]).InventoryFile)
+	assert.Equal(t, []string{"web2", "web1", "db1"}, cached.AllHosts().SortedNames(InventoryOrder))
+	assert.Equal(t, fresh.AllGroups().SortedNames(InventoryOrder), cached.AllGroups().SortedNames(InventoryOrder))
+	freshConn, err := fresh.Hosts["web2"].ConnectionWithDefaultPort(2222)
+	assert.Nil(t, err)
+	cachedConn, err := cached.Hosts["web2"].ConnectionWithDefaultPort(2222)
+	assert.Nil(t, err)
+	assert.Equal(t, ConnectionParam[int]{Value: 22, Explicit: true}, cachedConn.Port)
+	assert.Equal(t, freshConn, cachedConn)
+	issues := Lint(fresh, DefaultLintRules())
+	assert.NotEmpty(t, issues)
+	assert.NotEmpty(t, issues[0].File)
+	assert.Equal(t, issues, Lint(cached, DefaultLintRules()))
+	assert.Equal(t, fresh.inputPaths(), cached.inputPaths())
+
+	_, err = cached.SetHostVar(cached.Hosts["db1"], "serial", 1, VarsWriteOptions{})
+	assert.Nil(t, err)
+}
+
+func TestParseCacheWriteError(t *testing.T) {
+	root := t.TempDir()
+	inventoryPath := filepath.Join(root, "inventory")
+	assert.Nil(t, os.WriteFile(inventoryPath, []byte("[web]\nhost1\n"), 0o644))
+
+	// a file in place of the cache directory can't be written even by root
+	cacheDir := filepath.Join(root, "cache")
+	assert.Nil(t, os.WriteFile(cacheDir, nil, 0o444))
+	cache := &ParseCache{Dir: cacheDir}
+
+	v, err := cache.ParseFile(inventoryPath)
+	assert.Nil(t, err)
+	assert.Contains(t, v.Groups["web"].Hosts, "host1")
+
+	var writeErrors []error
+	cache.OnWriteError = func(entryPath string, err error) {
+		assert.Equal(t, cacheDir, filepath.Dir(entryPath))
+		writeErrors = append(writeErrors, err)
+	}
+	v, err = cache.ParseFile(inventoryPath)
+	assert.Nil(t, err)
+	assert.Contains(t, v.Groups["web"].Hosts, "host1")
+	assert.Len(t, writeErrors, 1)
+}
@@ -0,0 +1,113 @@
+package aini
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// NameConflictError is returned in case-insensitive mode when names of hosts or groups differ only by case
+type NameConflictError struct {
+	// Kind is "host" or "group"
+	Kind  string
+	Names []string
+}
+
+func (err *NameConflictError) Error() string {
+	return fmt.Sprintf("%s names differ only by case: %s", err.Kind, strings.Join(err.Names, ", "))
+}
+
+// SetCaseInsensitive makes MatchHostsByPatterns, MatchHosts, MatchGroups, LookupHost, LookupGroup and
+// lookup of group_vars and host_vars files compare names case-insensitively, while names keep their original case.
+//
+// It returns NameConflictError if there are hosts or groups whose names differ only by case.
+func (inventory *InventoryData) SetCaseInsensitive() error {
+	if err := inventory.checkCaseConflicts(); err != nil {
+		return err
+	}
+	inventory.caseInsensitive = true
+	return nil
+}
+
+// IsCaseInsensitive checks whether names are compared case-insensitively, as set by SetCaseInsensitive
+func (inventory *InventoryData) IsCaseInsensitive() bool {
+	return inventory.caseInsensitive
+}
+
+// LookupHost returns the host by name, case-insensitively if set by SetCaseInsensitive
+func (inventory *InventoryData) LookupHost(name string) (*Host, bool) {
+	return lookupName(inventory.Hosts, name, inventory.caseInsensitive)
+}
+
+// LookupGroup returns the group by name, case-insensitively if set by SetCaseInsensitive
+func (inventory *InventoryData) LookupGroup(name string) (*Group, bool) {
+	return lookupName(inventory.Groups, name, inventory.caseInsensitive)
+}
+
+func lookupName[V any](m map[string]V, name string, caseInsensitive bool) (V, bool) {
+	if value, ok := m[name]; ok || !caseInsensitive {
+		return value, ok
+	}
+	for key, value := range m {
+		if strings.EqualFold(key, name) {
+			return value, true
+		}
+	}
+	var zero V
+	return zero, false
+}
+
+// checkCaseConflicts returns NameConflictError for the first host or group names differing only by case
+func (inventory *InventoryData) checkCaseConflicts() error {
+	if conflicts := findCaseConflicts(inventory.Hosts); len(conflicts) > 0 {
+		return &NameConflictError{Kind: "host", Names: conflicts[0]}
+	}
+	if conflicts := findCaseConflicts(inventory.Groups); len(conflicts) > 0 {
+		return &NameConflictError{Kind: "group", Names: conflicts[0]}
+	}
+	return nil
+}
+
+// findCaseConflicts returns all sets of names differing only by case, each sorted and ordered by lowercased names
+func findCaseConflicts[V any](m map[string]V) [][]string {
+	byFolded := make(map[string][]string, len(m))
+	for name := range m {
+		folded := strings.ToLower(name)
+		byFolded[folded] = append(byFolded[folded], name)
+	}
+	conflicts := make([][]string, 0)
+	for _, folded := range sortedKeys(byFolded) {
+		if names := byFolded[folded]; len(names) > 1 {
+			sort.Strings(names)
+			conflicts = append(conflicts, names)
+		}
+	}
+	return conflicts
+}
+
+// MatchHostsCaseInsensitive does the same as MatchHosts, but compares names case-insensitively
+func MatchHostsCaseInsensitive(hosts map[string]*Host, pattern string) (map[string]*Host, error) {
+	return matchCaseInsensitive(hosts, pattern, func(host *Host) string { return host.Name })
+}
+
+// MatchGroupsCaseInsensitive does the same as MatchGroups, but compares names case-insensitively
+func MatchGroupsCaseInsensitive(groups map[string]*Group, pattern string) (map[string]*Group, error) {
+	return matchCaseInsensitive(groups, pattern, func(group *Group) string { return group.Name })
+}
+
+func matchCaseInsensitive[V any](items map[string]V, pattern string, nameOf func(V) string) (map[string]V, error) {
+	pattern = strings.ToLower(pattern)
+	matched := make(map[string]V)
+	for _, item := range items {
+		name := nameOf(item)
+		m, err := path.Match(pattern, strings.ToLower(name))
+		if err != nil {
+			return nil, err
+		}
+		if m {
+			matched[name] = item
+		}
+	}
+	return matched, nil
+}
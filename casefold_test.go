@@ -0,0 +1,76 @@
+package aini
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/exp/maps"
+)
+
+func TestCaseInsensitive(t *testing.T) {
+	v, err := ParseFile("test_data/inventory")
+	assert.Nil(t, err)
+	assert.False(t, v.IsCaseInsensitive())
+	assert.Nil(t, v.SetCaseInsensitive())
+	assert.True(t, v.IsCaseInsensitive())
+	assert.Nil(t, v.AddVars("test_data"))
+
+	host, ok := v.LookupHost("host7")
+	assert.True(t, ok)
+	assert.Equal(t, "Host7", host.Name)
+	assert.Equal(t, "string", host.Vars["host7_string_var"])
+
+	group, ok := v.LookupGroup("tomcat")
+	assert.True(t, ok)
+	assert.Equal(t, "TomCat", group.Name)
+	assert.Equal(t, "string", group.Vars["tomcat_string_var"])
+
+	hosts, err := v.MatchHostsByPatterns("TOMCAT:&HOST*")
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"Host7"}, maps.Keys(hosts))
+
+	hosts, err = v.MatchHosts("HOST7")
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"Host7"}, maps.Keys(hosts))
+
+	groups, err := v.MatchGroups("tom*")
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"TomCat"}, maps.Keys(groups))
+
+	groups, err = MatchGroups(v.Groups, "tom*")
+	assert.Nil(t, err)
+	assert.Empty(t, groups)
+}
+
+func TestCaseInsensitiveConflict(t *testing.T) {
+	v := parseString(t, `
+	Web1
+	web1
+	[Web]
+	db1
+	[web]
+	db2
+	`)
+	err := v.SetCaseInsensitive()
+	var conflictErr *NameConflictError
+	assert.True(t, errors.As(err, &conflictErr))
+	assert.Equal(t, "host", conflictErr.Kind)
+	assert.Equal(t, []string{"Web1", "web1"}, conflictErr.Names)
+	assert.False(t, v.IsCaseInsensitive())
+
+	delete(v.Hosts, "web1")
+	err = v.SetCaseInsensitive()
+	assert.True(t, errors.As(err, &conflictErr))
+	assert.Equal(t, "group", conflictErr.Kind)
+	assert.Equal(t, []string{"Web", "web"}, conflictErr.Names)
+}
+
+func TestLoadCaseInsensitive(t *testing.T) {
+	v, err := Load("test_data/inventory", WithCaseInsensitiveNames())
+	assert.Nil(t, err)
+	assert.True(t, v.HasHost("HOST7"))
+	assert.Contains(t, v.HostNames(), "Host7")
+	assert.Equal(t, "string", v.HostVars("host7")["host7_string_var"])
+	assert.Equal(t, "string", v.GroupVars("tomcat")["tomcat_string_var"])
+}
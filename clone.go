@@ -0,0 +1,231 @@
+package aini
+
+import "strings"
+
+// inventoryCopier copies hosts and groups once each, keeping links between them.
+// Copies are created first and linked later by link, to avoid deep recursion on large inventories.
+type inventoryCopier struct {
+	hosts         map[*Host]*Host
+	groups        map[*Group]*Group
+	pendingHosts  []*Host
+	pendingGroups []*Group
+}
+
+// Clone returns a deep copy of the inventory: hosts, groups and all their maps, linked to each other
+// the same way as in the inventory, and locations of definitions for Lint
+func (inventory *InventoryData) Clone() *InventoryData {
+	copier := &inventoryCopier{
+		hosts:  make(map[*Host]*Host, len(inventory.Hosts)),
+		groups: make(map[*Group]*Group, len(inventory.Groups)),
+	}
+	result := &InventoryData{
+		Groups:          copier.groupMap(inventory.Groups),
+		Hosts:           copier.hostMap(inventory.Hosts),
+		caseInsensitive: inventory.caseInsensitive,
+		path:            inventory.path,
+		varsRoots:       append([]varsRoot(nil), inventory.varsRoots...),
+		varsProblems:    append([]*VarsFileError(nil), inventory.varsProblems...),
+	}
+	copier.link()
+	if inventory.hostLines != nil {
+		result.hostLines = make(map[string][]hostDefinition, len(inventory.hostLines))
+		for name, definitions := range inventory.hostLines {
+			copied := make([]hostDefinition, len(definitions))
+			for i, definition := range definitions {
+				copied[i] = hostDefinition{line: definition.line, vars: cloneStringMap(definition.vars)}
+			}
+			result.hostLines[name] = copied
+		}
+	}
+	if inventory.groupLines != nil {
+		result.groupLines = make(map[string]int, len(inventory.groupLines))
+		for name, line := range inventory.groupLines {
+			result.groupLines[name] = line
+		}
+	}
+	if inventory.hostSequence != nil {
+		result.hostSequence = make(map[*Host]int, len(inventory.hostSequence))
+		for host, sequence := range inventory.hostSequence {
+			if copied, ok := copier.hosts[host]; ok {
+				result.hostSequence[copied] = sequence
+			}
+		}
+	}
+	if inventory.groupSequence != nil {
+		result.groupSequence = make(map[*Group]int, len(inventory.groupSequence))
+		for group, sequence := range inventory.groupSequence {
+			if copied, ok := copier.groups[group]; ok {
+				result.groupSequence[copied] = sequence
+			}
+		}
+	}
+	return result
+}
+
+func (copier *inventoryCopier) host(host *Host) *Host {
+	if copied, ok := copier.hosts[host]; ok {
+		return copied
+	}
+	copied := &Host{
+		Name:          host.Name,
+		Port:          host.Port,
+		Vars:          cloneStringMap(host.Vars),
+		InventoryVars: cloneStringMap(host.InventoryVars),
+		FileVars:      cloneStringMap(host.FileVars),
+		portSet:       host.portSet,
+	}
+	copier.hosts[host] = copied
+	copier.pendingHosts = append(copier.pendingHosts, host)
+	return copied
+}
+
+func (copier *inventoryCopier) group(group *Group) *Group {
+	if copied, ok := copier.groups[group]; ok {
+		return copied
+	}
+	copied := &Group{
+		Name:             group.Name,
+		Vars:             cloneStringMap(group.Vars),
+		InventoryVars:    cloneStringMap(group.InventoryVars),
+		FileVars:         cloneStringMap(group.FileVars),
+		AllInventoryVars: cloneStringMap(group.AllInventoryVars),
+		AllFileVars:      cloneStringMap(group.AllFileVars),
+	}
+	copier.groups[group] = copied
+	copier.pendingGroups = append(copier.pendingGroups, group)
+	return copied
+}
+
+// link sets relations of copied hosts and groups, copying more of them if not yet
+func (copier *inventoryCopier) link() {
+	for len(copier.pendingHosts) > 0 || len(copier.pendingGroups) > 0 {
+		if n := len(copier.pendingHosts); n > 0 {
+			host := copier.pendingHosts[n-1]
+			copier.pendingHosts = copier.pendingHosts[:n-1]
+			copied := copier.hosts[host]
+			copied.Groups = copier.groupMap(host.Groups)
+			copied.DirectGroups = copier.groupMap(host.DirectGroups)
+			continue
+		}
+		n := len(copier.pendingGroups)
+		group := copier.pendingGroups[n-1]
+		copier.pendingGroups = copier.pendingGroups[:n-1]
+		copied := copier.groups[group]
+		copied.Hosts = copier.hostMap(group.Hosts)
+		copied.Children = copier.groupMap(group.Children)
+		copied.Parents = copier.groupMap(group.Parents)
+		copied.DirectParents = copier.groupMap(group.DirectParents)
+	}
+}
+
+// hostMap copies the map with the same keys, which may differ from names of hosts
+func (copier *inventoryCopier) hostMap(hosts map[string]*Host) map[string]*Host {
+	if hosts == nil {
+		return nil
+	}
+	result := make(map[string]*Host, len(hosts))
+	for key, host := range hosts {
+		result[key] = copier.host(host)
+	}
+	return result
+}
+
+// groupMap copies the map with the same keys, which may differ from names of groups
+func (copier *inventoryCopier) groupMap(groups map[string]*Group) map[string]*Group {
+	if groups == nil {
+		return nil
+	}
+	result := make(map[string]*Group, len(groups))
+	for key, group := range groups {
+		result[key] = copier.group(group)
+	}
+	return result
+}
+
+// cloneStringMap copies the map, keeping nil as nil
+func cloneStringMap(from map[string]string) map[string]string {
+	if from == nil {
+		return nil
+	}
+	return copyStringMap(from)
+}
+
+// Subset returns a reconciled copy of the inventory with only hosts matching Ansible host patterns,
+// as MatchHostsByPatterns, and groups they belong to directly or through descendant groups
+func (inventory *InventoryData) Subset(patterns string) (*InventoryData, error) {
+	hosts, err := inventory.MatchHostsByPatterns(patterns)
+	if err != nil {
+		return nil, err
+	}
+	return inventory.subset(func(host *Host) bool {
+		_, ok := hosts[host.Name]
+		return ok
+	}), nil
+}
+
+// SubsetHosts returns a reconciled copy of the inventory with only hosts of the same names in the set,
+// and groups they belong to directly or through descendant groups
+func (inventory *InventoryData) SubsetHosts(hosts *HostSet) *InventoryData {
+	return inventory.subset(func(host *Host) bool {
+		return hosts.Contains(host.Name)
+	})
+}
+
+// subset returns a copy of the inventory with hosts selected by keep and their ancestor groups.
+// The groups all and ungrouped are always kept with their variables.
+func (inventory *InventoryData) subset(keep func(host *Host) bool) *InventoryData {
+	result := inventory.Clone()
+	hosts := make(map[string]*Host)
+	groups := make(map[string]*Group)
+	for _, name := range []string{"all", "ungrouped"} {
+		if group, ok := result.Groups[name]; ok {
+			groups[name] = group
+		}
+	}
+	for key, host := range result.Hosts {
+		if !keep(host) {
+			continue
+		}
+		hosts[key] = host
+		for _, group := range host.DirectGroups {
+			groups[group.Name] = group
+			for _, ancestor := range group.ListParentGroupsOrdered() {
+				groups[ancestor.Name] = ancestor
+			}
+		}
+	}
+	result.Hosts, result.Groups = hosts, groups
+
+	// locations are by original names, which may differ in case after HostsToLower and GroupsToLower
+	hostNames, groupNames := lowerKeys(hosts), lowerKeys(groups)
+	for name := range result.hostLines {
+		if !hostNames[strings.ToLower(name)] {
+			delete(result.hostLines, name)
+		}
+	}
+	for name := range result.groupLines {
+		if !groupNames[strings.ToLower(name)] {
+			delete(result.groupLines, name)
+		}
+	}
+	for host := range result.hostSequence {
+		if !hostNames[strings.ToLower(host.Name)] {
+			delete(result.hostSequence, host)
+		}
+	}
+	for group := range result.groupSequence {
+		if !groupNames[strings.ToLower(group.Name)] {
+			delete(result.groupSequence, group)
+		}
+	}
+	result.Reconcile()
+	return result
+}
+
+func lowerKeys[V any](m map[string]V) map[string]bool {
+	keys := make(map[string]bool, len(m))
+	for key := range m {
+		keys[strings.ToLower(key)] = true
+	}
+	return keys
+}
@@ -0,0 +1,88 @@
+package aini
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClone(t *testing.T) {
+	v := parseString(t, `
+	web1
+	[web]
+	web1 env=prod
+	[dc:children]
+	web
+	`)
+	copied := v.Clone()
+
+	assert.Equal(t, v.Hosts["web1"].Vars, copied.Hosts["web1"].Vars)
+	assert.NotSame(t, v.Hosts["web1"], copied.Hosts["web1"])
+	// links point to copies
+	assert.Same(t, copied.Groups["web"], copied.Hosts["web1"].DirectGroups["web"])
+	assert.Same(t, copied.Hosts["web1"], copied.Groups["dc"].Hosts["web1"])
+	assert.Same(t, copied.Groups["dc"], copied.Groups["web"].DirectParents["dc"])
+	assert.Same(t, copied.Groups["web"], copied.Groups["dc"].Children["web"])
+
+	// source locations are kept
+	assert.Equal(t, Lint(v, DefaultLintRules()), Lint(copied, DefaultLintRules()))
+	assert.Equal(t, []string{"web1"}, copied.AllHosts().SortedNames(InventoryOrder))
+	assert.Equal(t, v.AllGroups().SortedNames(InventoryOrder), copied.AllGroups().SortedNames(InventoryOrder))
+}
+
+func TestSubset(t *testing.T) {
+	v := parseString(t, `
+	lonely
+	[web]
+	web1 env=prod
+	web2
+	[db]
+	db1
+	[eu:children]
+	web
+	[eu:vars]
+	region=eu
+	[all:vars]
+	owner=ops
+	`)
+	subset, err := v.Subset("web:!web2")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"web1"}, sortedKeys(subset.Hosts))
+	assert.Equal(t, []string{"all", "eu", "ungrouped", "web"}, sortedKeys(subset.Groups))
+	web1 := subset.Hosts["web1"]
+	assert.Equal(t, map[string]string{"env": "prod", "region": "eu", "owner": "ops"}, web1.Vars)
+	assert.Equal(t, []string{"web1"}, sortedKeys(subset.Groups["eu"].Hosts))
+	assert.Equal(t, []string{"eu", "ungrouped", "web"}, sortedKeys(subset.Groups["all"].Children))
+	assert.Empty(t, subset.Groups["ungrouped"].Hosts)
+	// the original is intact
+	assert.Len(t, v.Hosts, 4)
+	assert.Len(t, v.Groups["web"].Hosts, 2)
+	assert.NotSame(t, v.Hosts["web1"], web1)
+
+	// no issues of removed hosts and groups
+	rule, _ := LintRuleByName("ungrouped-host")
+	assert.NotEmpty(t, Lint(v, []LintRule{rule}))
+	assert.Empty(t, Lint(subset, []LintRule{rule}))
+	assert.Equal(t, []string{"web1"}, subset.AllHosts().SortedNames(InventoryOrder))
+
+	_, err = v.Subset("!web")
+	assert.NotNil(t, err)
+}
+
+func TestSubsetHosts(t *testing.T) {
+	v := parseString(t, `
+	[web]
+	web1
+	web2
+	[db]
+	db1
+	`)
+	index := v.BuildIndex()
+	subset := v.SubsetHosts(index.Group("db").Union(index.Host("web2")))
+	assert.Equal(t, []string{"db1", "web2"}, sortedKeys(subset.Hosts))
+	assert.Equal(t, []string{"all", "db", "ungrouped", "web"}, sortedKeys(subset.Groups))
+
+	empty := v.SubsetHosts(NewHostSet())
+	assert.Empty(t, empty.Hosts)
+	assert.Equal(t, []string{"all", "ungrouped"}, sortedKeys(empty.Groups))
+}
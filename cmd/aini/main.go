@@ -0,0 +1,163 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/relex/aini"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	switch os.Args[1] {
+	case "lint":
+		os.Exit(runLint(os.Args[2:]))
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: aini lint [options] inventory_file_or_host_list")
+	os.Exit(2)
+}
+
+var severityRanks = map[aini.LintSeverity]int{
+	aini.LintInfo:    1,
+	aini.LintWarning: 2,
+	aini.LintError:   3,
+}
+
+func runLint(args []string) int {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	format := flags.String("format", "text", "output format: text, json or sarif")
+	enable := flags.String("rules", "", "comma-separated rules to run, all rules if empty")
+	disable := flags.String("disable", "", "comma-separated rules not to run")
+	severities := flags.String("severity", "", "comma-separated severity overrides, e.g. empty-group=error,ungrouped-host=warning")
+	failOn := flags.String("fail-on", "error", "lowest severity of issues to fail on: error, warning or info")
+	lowercase := flags.Bool("lowercase", false, "convert host and group names to lowercase before checking, which disables case-conflict")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: aini lint [options] inventory_file_or_host_list")
+		fmt.Fprintln(os.Stderr, "\nRules:")
+		for _, rule := range aini.DefaultLintRules() {
+			fmt.Fprintf(os.Stderr, "  %-30s %-8s %s\n", rule.Name, rule.Severity, rule.Description)
+		}
+		fmt.Fprintln(os.Stderr, "\nOptions:")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	rules, err := selectRules(*enable, *disable, *severities)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid rule options: %v\n", err)
+		return 2
+	}
+	failRank, ok := severityRanks[aini.LintSeverity(*failOn)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Invalid severity: %s\n", *failOn)
+		return 2
+	}
+
+	options := []aini.Option{}
+	if *lowercase {
+		options = append(options, aini.WithLowercaseNames())
+		for _, rule := range rules {
+			if rule.Name == "case-conflict" {
+				fmt.Fprintln(os.Stderr, "Warning: case-conflict cannot find conflicts with -lowercase, as names are merged before checking")
+			}
+		}
+	}
+	inventory, err := aini.Load(flags.Arg(0), options...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load inventory %s: %v\n", flags.Arg(0), err)
+		return 2
+	}
+
+	issues := inventory.Lint(rules)
+
+	switch *format {
+	case "json":
+		err = aini.WriteLintJSON(os.Stdout, issues)
+	case "sarif":
+		err = aini.WriteLintSARIF(os.Stdout, issues, rules)
+	case "text":
+		for _, issue := range issues {
+			location := issue.File
+			if issue.Line > 0 {
+				location = fmt.Sprintf("%s:%d", issue.File, issue.Line)
+			}
+			fmt.Printf("%s: %s: %s [%s]\n", location, issue.Severity, issue.Message, issue.Rule)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "Invalid format: %s\n", *format)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write lint result: %v\n", err)
+		return 2
+	}
+
+	for _, issue := range issues {
+		if severityRanks[issue.Severity] >= failRank {
+			return 1
+		}
+	}
+	return 0
+}
+
+// selectRules returns the enabled built-in rules with severities overridden
+func selectRules(enable string, disable string, severities string) ([]aini.LintRule, error) {
+	rules := aini.DefaultLintRules()
+	if enable != "" {
+		rules = nil
+		for _, name := range strings.Split(enable, ",") {
+			rule, ok := aini.LintRuleByName(strings.TrimSpace(name))
+			if !ok {
+				return nil, fmt.Errorf("unknown rule: %s", name)
+			}
+			rules = append(rules, rule)
+		}
+	}
+	if disable != "" {
+		disabled := make(map[string]bool)
+		for _, name := range strings.Split(disable, ",") {
+			name = strings.TrimSpace(name)
+			if _, ok := aini.LintRuleByName(name); !ok {
+				return nil, fmt.Errorf("unknown rule: %s", name)
+			}
+			disabled[name] = true
+		}
+		enabled := rules[:0]
+		for _, rule := range rules {
+			if !disabled[rule.Name] {
+				enabled = append(enabled, rule)
+			}
+		}
+		rules = enabled
+	}
+	if severities != "" {
+		for _, override := range strings.Split(severities, ",") {
+			name, severity, found := strings.Cut(strings.TrimSpace(override), "=")
+			if _, ok := severityRanks[aini.LintSeverity(severity)]; !found || !ok {
+				return nil, fmt.Errorf("invalid severity override: %s", override)
+			}
+			if _, ok := aini.LintRuleByName(name); !ok {
+				return nil, fmt.Errorf("unknown rule: %s", name)
+			}
+			for i := range rules {
+				if rules[i].Name == name {
+					rules[i].Severity = aini.LintSeverity(severity)
+				}
+			}
+		}
+	}
+	return rules, nil
+}
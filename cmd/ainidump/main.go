@@ -1,80 +1,150 @@
 package main
 
 import (
+	"bufio"
 	"encoding/json"
+	"flag"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
 	"slices"
-	"sort"
-	"strings"
 
 	"github.com/relex/aini"
-	"github.com/samber/lo"
-	"golang.org/x/exp/maps"
 )
 
 func main() {
-	if len(os.Args) < 2 || len(os.Args) > 3 {
-		fmt.Fprintln(os.Stderr, "Usage: ainidump inventory_file [host_or_group_patterns]")
-		os.Exit(1)
+	where := flag.String("where", "", "select hosts by expression over variables and groups, e.g. 'env == \"prod\" and \"web\" in groups'")
+	explain := flag.Bool("explain", false, "explain why each host matches the patterns or not, instead of dumping matched hosts")
+	magicVars := flag.Bool("magic-vars", false, "include Ansible magic variables of hosts except hostvars, e.g. group_names and inventory_dir")
+	sortName := flag.String("sort", "lexical", "order of listed hosts and groups: lexical, natural (web2 before web10) or inventory")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: ainidump [--where expression] [--explain] [--sort order] [--magic-vars] inventory_file_or_host_list [host_or_group_patterns]")
+		flag.PrintDefaults()
 	}
-
-	inventoryPath, err := filepath.Abs(os.Args[1])
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to resolve inventory file path %s: %v\n", os.Args[1], err)
-		os.Exit(2)
+	flag.Parse()
+	if flag.NArg() < 1 || flag.NArg() > 2 {
+		flag.Usage()
+		os.Exit(1)
 	}
 
-	inventory, err := aini.ParseFile(inventoryPath)
+	order, err := aini.ParseSortOrder(*sortName)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to parse inventory file %s: %v\n", inventoryPath, err)
-		os.Exit(3)
+		fmt.Fprintf(os.Stderr, "Invalid --sort: %v\n", err)
+		os.Exit(1)
 	}
 
-	inventory.HostsToLower()
-	inventory.GroupsToLower()
-
-	inventoryDir := filepath.Dir(inventoryPath)
-	if err := inventory.AddVarsLowerCased(inventoryDir); err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to load inventory variables %s: %v\n", inventoryDir, err)
-		os.Exit(4)
+	var query *aini.Query
+	if *where != "" {
+		query, err = aini.CompileQuery(*where)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to compile query: %v\n", err)
+			os.Exit(1)
+		}
 	}
 
-	if len(os.Args) == 2 {
-		result := exportResult(inventory.Hosts, inventory.Groups)
-		j, err := json.MarshalIndent(result, "", "    ")
+	var inventory *aini.InventoryData
+	if aini.IsHostList(flag.Arg(0)) {
+		inventory, err = aini.ParseHostList(flag.Arg(0))
 		if err != nil {
-			panic(err)
+			fmt.Fprintf(os.Stderr, "Failed to parse host list %s: %v\n", flag.Arg(0), err)
+			os.Exit(3)
 		}
-		fmt.Println(string(j))
+		inventory.HostsToLower()
+		inventory.GroupsToLower()
+	} else {
+		inventory = loadInventoryFile(flag.Arg(0))
+	}
+
+	if flag.NArg() == 1 && query == nil && !*explain {
+		writeOutput(func(w *bufio.Writer) error {
+			return writeResult(w, inventory, order, hostMagicVars(inventory, *magicVars))
+		})
 		return
 	}
 
-	patterns := os.Args[2]
+	patterns := "all"
+	if flag.NArg() == 2 {
+		patterns = flag.Arg(1)
+	}
+
+	if *explain {
+		explainPatterns(inventory, patterns, order)
+		return
+	}
 
-	matchedHostsMap, err := inventory.MatchHostsByPatterns(patterns)
+	var matchedHostsMap map[string]*aini.Host
+	if query != nil {
+		matchedHostsMap, err = inventory.MatchHostsByPatternsAndQuery(patterns, query)
+	} else {
+		matchedHostsMap, err = inventory.MatchHostsByPatterns(patterns)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to match hosts with patterns %s: %v\n", patterns, err)
 		os.Exit(5)
 	}
-	j, err := json.MarshalIndent(lo.MapEntries(matchedHostsMap, func(name string, host *aini.Host) (string, ResultHost) {
-		return host.Name, ResultHost{
-			Name:   host.Name,
-			Groups: getGroupNames(host.ListGroupsOrdered()),
-			Vars:   host.Vars,
+	writeOutput(func(w *bufio.Writer) error {
+		return writeMatchedHosts(w, matchedHostsMap, order, hostMagicVars(inventory, *magicVars))
+	})
+}
+
+func explainPatterns(inventory *aini.InventoryData, patterns string, order aini.SortOrder) {
+	pattern, err := aini.CompilePattern(patterns)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to compile patterns %s: %v\n", patterns, err)
+		os.Exit(5)
+	}
+	for host := range inventory.AllHosts().All(order) {
+		fmt.Println(pattern.ExplainIn(inventory, host))
+	}
+}
+
+func loadInventoryFile(path string) *aini.InventoryData {
+	inventoryPath, err := filepath.Abs(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to resolve inventory file path %s: %v\n", path, err)
+		os.Exit(2)
+	}
+
+	inventoryDir := filepath.Dir(inventoryPath)
+	if cacheDir := os.Getenv("AINIDUMP_CACHE_DIR"); cacheDir != "" {
+		cache, err := aini.NewParseCache(cacheDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to create cache directory %s: %v\n", cacheDir, err)
+			os.Exit(2)
+		}
+		cache.OnWriteError = func(entryPath string, err error) {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		inventory, err := cache.ParseFileLowerCased(inventoryPath, inventoryDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load inventory %s: %v\n", inventoryPath, err)
+			os.Exit(3)
 		}
-	}), "", "    ")
+		return inventory
+	}
+
+	inventory, err := aini.ParseFile(inventoryPath)
 	if err != nil {
-		panic(err)
+		fmt.Fprintf(os.Stderr, "Failed to parse inventory file %s: %v\n", inventoryPath, err)
+		os.Exit(3)
+	}
+
+	inventory.HostsToLower()
+	inventory.GroupsToLower()
+
+	if err := inventory.AddVarsLowerCased(inventoryDir); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load inventory variables %s: %v\n", inventoryDir, err)
+		os.Exit(4)
 	}
-	fmt.Println(string(j))
+	return inventory
 }
 
 type ResultHost struct {
-	Name   string
-	Groups []string
-	Vars   map[string]string
+	Name      string
+	Groups    []string
+	Vars      map[string]string
+	MagicVars *aini.MagicVars `json:",omitempty"`
 }
 type ResultGroup struct {
 	Name        string
@@ -84,49 +154,109 @@ type ResultGroup struct {
 	Vars        map[string]string
 }
 
-func exportResult(hostMap map[string]*aini.Host, groupMap map[string]*aini.Group) any {
-	type Result struct {
-		Hosts  []ResultHost
-		Groups []ResultGroup
+// hostMagicVars returns the function computing magic variables of hosts except hostvars, nil if not enabled.
+// hostvars would repeat variables of all hosts for every host.
+func hostMagicVars(inventory *aini.InventoryData, enabled bool) func(host *aini.Host) aini.MagicVars {
+	if !enabled {
+		return nil
+	}
+	return inventory.MagicVarsFunc(aini.MagicVarsOptions{SkipHostVars: true})
+}
+
+// writeOutput writes JSON output to stdout, exiting on failures
+func writeOutput(write func(w *bufio.Writer) error) {
+	w := bufio.NewWriter(os.Stdout)
+	err := write(w)
+	if err == nil {
+		err = w.Flush()
 	}
-	result := &Result{
-		Hosts:  make([]ResultHost, 0, len(hostMap)),
-		Groups: make([]ResultGroup, 0, len(groupMap)),
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write output: %v\n", err)
+		os.Exit(6)
 	}
+}
 
-	orderedHosts := maps.Values(hostMap)
-	slices.SortStableFunc(orderedHosts, func(a, b *aini.Host) int {
-		return strings.Compare(a.Name, b.Name)
-	})
-	for _, host := range orderedHosts {
-		result.Hosts = append(result.Hosts, ResultHost{
-			Name:   host.Name,
-			Groups: getGroupNames(host.ListGroupsOrdered()),
-			Vars:   host.Vars,
+// writeResult writes all hosts and groups, marshaling hosts one by one as with magic variables
+// every host lists all groups with their hosts
+func writeResult(w *bufio.Writer, inventory *aini.InventoryData, order aini.SortOrder, magicVars func(host *aini.Host) aini.MagicVars) error {
+	groups := make([]ResultGroup, 0, len(inventory.Groups))
+	for group := range inventory.AllGroups().All(order) {
+		groups = append(groups, ResultGroup{
+			Name:        group.Name,
+			Parents:     getGroupNames(group.ListParentGroupsOrderedBy(order)),
+			Descendants: inventory.GroupSet(group.Children).SortedNames(order),
+			Hosts:       inventory.HostSet(group.Hosts).SortedNames(order),
+			Vars:        group.Vars,
 		})
 	}
 
-	orderedGroups := maps.Values(groupMap)
-	slices.SortStableFunc(orderedGroups, func(a, b *aini.Group) int {
-		return strings.Compare(a.Name, b.Name)
-	})
-	for _, group := range orderedGroups {
-		orderedDescendantNames := getGroupNames(maps.Values(group.Children))
-		sort.Strings(orderedDescendantNames)
+	w.WriteString("{\n    \"Hosts\": [")
+	count := 0
+	for host := range inventory.AllHosts().All(order) {
+		if count > 0 {
+			w.WriteString(",")
+		}
+		w.WriteString("\n        ")
+		if err := writeIndentedJSON(w, exportHost(host, order, magicVars), "        "); err != nil {
+			return err
+		}
+		count++
+	}
+	if count > 0 {
+		w.WriteString("\n    ")
+	}
+	w.WriteString("],\n    \"Groups\": ")
+	if err := writeIndentedJSON(w, groups, "    "); err != nil {
+		return err
+	}
+	_, err := w.WriteString("\n}\n")
+	return err
+}
 
-		orderedHostNames := getHostNames(maps.Values(group.Hosts))
-		sort.Strings(orderedHostNames)
+// writeMatchedHosts writes matched hosts by names in lexical order, marshaling them one by one as writeResult
+func writeMatchedHosts(w *bufio.Writer, hosts map[string]*aini.Host, order aini.SortOrder, magicVars func(host *aini.Host) aini.MagicVars) error {
+	w.WriteString("{")
+	for index, name := range slices.Sorted(maps.Keys(hosts)) {
+		if index > 0 {
+			w.WriteString(",")
+		}
+		w.WriteString("\n    ")
+		if err := writeIndentedJSON(w, name, "    "); err != nil {
+			return err
+		}
+		w.WriteString(": ")
+		if err := writeIndentedJSON(w, exportHost(hosts[name], order, magicVars), "    "); err != nil {
+			return err
+		}
+	}
+	if len(hosts) > 0 {
+		w.WriteString("\n")
+	}
+	_, err := w.WriteString("}\n")
+	return err
+}
 
-		result.Groups = append(result.Groups, ResultGroup{
-			Name:        group.Name,
-			Parents:     getGroupNames(group.ListParentGroupsOrdered()),
-			Descendants: orderedDescendantNames,
-			Hosts:       orderedHostNames,
-			Vars:        group.Vars,
-		})
+// writeIndentedJSON writes the value indented by 4 spaces, nested at the level of the prefix
+func writeIndentedJSON(w *bufio.Writer, value any, prefix string) error {
+	j, err := json.MarshalIndent(value, prefix, "    ")
+	if err != nil {
+		return err
 	}
+	_, err = w.Write(j)
+	return err
+}
 
-	return &result
+func exportHost(host *aini.Host, order aini.SortOrder, magicVars func(host *aini.Host) aini.MagicVars) ResultHost {
+	result := ResultHost{
+		Name:   host.Name,
+		Groups: getGroupNames(host.ListGroupsOrderedBy(order)),
+		Vars:   host.Vars,
+	}
+	if magicVars != nil {
+		magic := magicVars(host)
+		result.MagicVars = &magic
+	}
+	return result
 }
 
 func getGroupNames(groups []*aini.Group) []string {
@@ -136,11 +266,3 @@ func getGroupNames(groups []*aini.Group) []string {
 	}
 	return groupNames
 }
-
-func getHostNames(hosts []*aini.Host) []string {
-	hostNames := make([]string, 0, len(hosts))
-	for _, hst := range hosts {
-		hostNames = append(hostNames, hst.Name)
-	}
-	return hostNames
-}
@@ -0,0 +1,119 @@
+package aini
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// defaultPort is the SSH port assumed for hosts without explicit port
+const defaultPort = 22
+
+// ConnectionParam is a single resolved connection parameter
+type ConnectionParam[T any] struct {
+	Value T
+	// Explicit is true if the value was set in variables or on the host line, false if it's a default
+	Explicit bool
+	// Source is the name of the variable the value was taken from, empty if not taken from variables
+	Source string
+}
+
+// Connection contains parameters used by Ansible to connect to a host
+type Connection struct {
+	Host           ConnectionParam[string] // ansible_host, ansible_ssh_host; defaults to the inventory hostname
+	Port           ConnectionParam[int]    // ansible_port, ansible_ssh_port; defaults to the host line port
+	User           ConnectionParam[string] // ansible_user, ansible_ssh_user
+	Password       ConnectionParam[string] // ansible_password, ansible_ssh_pass, ansible_ssh_password
+	PrivateKeyFile ConnectionParam[string] // ansible_private_key_file, ansible_ssh_private_key_file
+	Type           ConnectionParam[string] // ansible_connection; defaults to "ssh"
+	Become         ConnectionParam[bool]   // ansible_become
+	BecomeMethod   ConnectionParam[string] // ansible_become_method; defaults to "sudo"
+	BecomeUser     ConnectionParam[string] // ansible_become_user; defaults to "root"
+	BecomePassword ConnectionParam[string] // ansible_become_password, ansible_become_pass
+}
+
+// Variable names for each connection parameter, in order of precedence
+var (
+	connectionHostVars           = []string{"ansible_host", "ansible_ssh_host"}
+	connectionPortVars           = []string{"ansible_port", "ansible_ssh_port"}
+	connectionUserVars           = []string{"ansible_user", "ansible_ssh_user"}
+	connectionPasswordVars       = []string{"ansible_password", "ansible_ssh_pass", "ansible_ssh_password"}
+	connectionPrivateKeyFileVars = []string{"ansible_private_key_file", "ansible_ssh_private_key_file"}
+	connectionTypeVars           = []string{"ansible_connection"}
+	connectionBecomeVars         = []string{"ansible_become"}
+	connectionBecomeMethodVars   = []string{"ansible_become_method"}
+	connectionBecomeUserVars     = []string{"ansible_become_user"}
+	connectionBecomePasswordVars = []string{"ansible_become_password", "ansible_become_pass"}
+)
+
+// Connection resolves connection parameters of the host from its variables, using 22 as default port
+func (host *Host) Connection() (Connection, error) {
+	return host.ConnectionWithDefaultPort(defaultPort)
+}
+
+// ConnectionWithDefaultPort resolves connection parameters of the host from its variables.
+//
+// The port is resolved in order: port variables, port on the host line, the given default port.
+// Host.Port is considered set on the host line if it was parsed from there or it differs from 22.
+func (host *Host) ConnectionWithDefaultPort(port int) (Connection, error) {
+	conn := Connection{
+		Host:         resolveStringParam(host.Vars, connectionHostVars, host.Name),
+		Port:         ConnectionParam[int]{Value: port},
+		User:         resolveStringParam(host.Vars, connectionUserVars, ""),
+		Password:     resolveStringParam(host.Vars, connectionPasswordVars, ""),
+		Type:         resolveStringParam(host.Vars, connectionTypeVars, "ssh"),
+		BecomeMethod: resolveStringParam(host.Vars, connectionBecomeMethodVars, "sudo"),
+		BecomeUser:   resolveStringParam(host.Vars, connectionBecomeUserVars, "root"),
+
+		PrivateKeyFile: resolveStringParam(host.Vars, connectionPrivateKeyFileVars, ""),
+		BecomePassword: resolveStringParam(host.Vars, connectionBecomePasswordVars, ""),
+	}
+
+	if name, value, ok := lookupVar(host.Vars, connectionPortVars); ok {
+		p, err := strconv.Atoi(value)
+		if err != nil {
+			return conn, fmt.Errorf("host %s: invalid %s value '%s': %w", host.Name, name, value, err)
+		}
+		conn.Port = ConnectionParam[int]{Value: p, Explicit: true, Source: name}
+	} else if host.portSet || (host.Port != 0 && host.Port != defaultPort) {
+		conn.Port = ConnectionParam[int]{Value: host.Port, Explicit: true}
+	}
+
+	if name, value, ok := lookupVar(host.Vars, connectionBecomeVars); ok {
+		b, err := parseBool(value)
+		if err != nil {
+			return conn, fmt.Errorf("host %s: invalid %s value '%s': %w", host.Name, name, value, err)
+		}
+		conn.Become = ConnectionParam[bool]{Value: b, Explicit: true, Source: name}
+	}
+
+	return conn, nil
+}
+
+// lookupVar returns the first variable present in vars from the given names
+func lookupVar(vars map[string]string, names []string) (string, string, bool) {
+	for _, name := range names {
+		if value, ok := vars[name]; ok {
+			return name, value, true
+		}
+	}
+	return "", "", false
+}
+
+func resolveStringParam(vars map[string]string, names []string, defaultValue string) ConnectionParam[string] {
+	if name, value, ok := lookupVar(vars, names); ok {
+		return ConnectionParam[string]{Value: value, Explicit: true, Source: name}
+	}
+	return ConnectionParam[string]{Value: defaultValue}
+}
+
+// parseBool parses boolean values the way Ansible does, e.g. "yes", "True", "on", "1"
+func parseBool(value string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "yes", "y", "true", "t", "on", "1":
+		return true, nil
+	case "no", "n", "false", "f", "off", "0", "":
+		return false, nil
+	}
+	return false, fmt.Errorf("not a boolean: %s", value)
+}
@@ -0,0 +1,70 @@
+package aini
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConnection(t *testing.T) {
+	v := parseString(t, `
+	host1
+	host2:2222
+	host3:2222 ansible_port=2323
+	host4 ansible_ssh_host=10.0.0.4 ansible_ssh_port=2424 ansible_ssh_user=admin
+	host5 ansible_host=10.0.0.5 ansible_ssh_host=10.0.0.50 ansible_become=yes ansible_become_user=app
+	host6 ansible_port=abc
+	host7:22
+
+	[local]
+	localhost
+
+	[local:vars]
+	ansible_connection=local
+	`)
+
+	conn, err := v.Hosts["host1"].Connection()
+	assert.Nil(t, err)
+	assert.Equal(t, ConnectionParam[string]{Value: "host1"}, conn.Host)
+	assert.Equal(t, ConnectionParam[int]{Value: 22}, conn.Port)
+	assert.Equal(t, ConnectionParam[string]{Value: "ssh"}, conn.Type)
+	assert.Equal(t, ConnectionParam[bool]{Value: false}, conn.Become)
+	assert.Equal(t, ConnectionParam[string]{Value: "root"}, conn.BecomeUser)
+
+	conn, err = v.Hosts["host1"].ConnectionWithDefaultPort(2200)
+	assert.Nil(t, err)
+	assert.Equal(t, ConnectionParam[int]{Value: 2200}, conn.Port)
+
+	conn, err = v.Hosts["host2"].ConnectionWithDefaultPort(2200)
+	assert.Nil(t, err)
+	assert.Equal(t, ConnectionParam[int]{Value: 2222, Explicit: true}, conn.Port)
+
+	conn, err = v.Hosts["host3"].Connection()
+	assert.Nil(t, err)
+	assert.Equal(t, ConnectionParam[int]{Value: 2323, Explicit: true, Source: "ansible_port"}, conn.Port)
+
+	conn, err = v.Hosts["host4"].Connection()
+	assert.Nil(t, err)
+	assert.Equal(t, ConnectionParam[string]{Value: "10.0.0.4", Explicit: true, Source: "ansible_ssh_host"}, conn.Host)
+	assert.Equal(t, ConnectionParam[int]{Value: 2424, Explicit: true, Source: "ansible_ssh_port"}, conn.Port)
+	assert.Equal(t, ConnectionParam[string]{Value: "admin", Explicit: true, Source: "ansible_ssh_user"}, conn.User)
+
+	conn, err = v.Hosts["host5"].Connection()
+	assert.Nil(t, err)
+	assert.Equal(t, ConnectionParam[string]{Value: "10.0.0.5", Explicit: true, Source: "ansible_host"}, conn.Host)
+	assert.Equal(t, ConnectionParam[bool]{Value: true, Explicit: true, Source: "ansible_become"}, conn.Become)
+	assert.Equal(t, ConnectionParam[string]{Value: "app", Explicit: true, Source: "ansible_become_user"}, conn.BecomeUser)
+
+	_, err = v.Hosts["host6"].Connection()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "ansible_port")
+	}
+
+	conn, err = v.Hosts["host7"].ConnectionWithDefaultPort(2200)
+	assert.Nil(t, err)
+	assert.Equal(t, ConnectionParam[int]{Value: 22, Explicit: true}, conn.Port)
+
+	conn, err = v.Hosts["localhost"].Connection()
+	assert.Nil(t, err)
+	assert.Equal(t, ConnectionParam[string]{Value: "local", Explicit: true, Source: "ansible_connection"}, conn.Type)
+}
@@ -0,0 +1,349 @@
+package aini
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConstructedConfig is the configuration of Ansible's `constructed` inventory plugin,
+// see https://docs.ansible.com/ansible/latest/collections/ansible/builtin/constructed_inventory.html
+type ConstructedConfig struct {
+	Plugin string `yaml:"plugin"`
+	// Strict makes errors in expressions fail, instead of skipping the variable or group for the host
+	Strict bool `yaml:"strict"`
+	// Compose creates variables from expressions, in the order of definition
+	Compose NamedExpressions `yaml:"compose"`
+	// Groups adds hosts to groups whose conditional expressions are true for them
+	Groups NamedExpressions `yaml:"groups"`
+	// KeyedGroups adds hosts to groups named after variable values
+	KeyedGroups []KeyedGroup `yaml:"keyed_groups"`
+	// LeadingSeparator adds separator to names of keyed groups without prefix, true if not set
+	LeadingSeparator *bool `yaml:"leading_separator"`
+}
+
+// KeyedGroup defines groups created from values of a key expression
+type KeyedGroup struct {
+	Key    string `yaml:"key"`
+	Prefix string `yaml:"prefix"`
+	// Separator between prefix and value, "_" if not set
+	Separator *string `yaml:"separator"`
+	// ParentGroup is made a parent of all the created groups
+	ParentGroup string `yaml:"parent_group"`
+	// DefaultValue replaces empty values
+	DefaultValue *string `yaml:"default_value"`
+	// TrailingSeparator keeps separator after prefix for empty values, true if not set
+	TrailingSeparator *bool `yaml:"trailing_separator"`
+}
+
+// NamedExpression is a name and Jinja2 expression pair
+type NamedExpression struct {
+	Name       string
+	Expression string
+}
+
+// NamedExpressions is an ordered list of name and expression pairs, defined as a YAML mapping
+type NamedExpressions []NamedExpression
+
+// UnmarshalYAML decodes YAML mapping preserving order of keys
+func (list *NamedExpressions) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: expected a mapping of names to expressions", node.Line)
+	}
+	*list = make(NamedExpressions, 0, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if value.Kind != yaml.ScalarNode {
+			return fmt.Errorf("line %d: expression of %s must be a string", value.Line, key.Value)
+		}
+		*list = append(*list, NamedExpression{Name: key.Value, Expression: value.Value})
+	}
+	return nil
+}
+
+// LoadConstructedConfig loads the configuration of constructed inventory from a YAML file
+func LoadConstructedConfig(path string) (*ConstructedConfig, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseConstructedConfig(f)
+}
+
+// ParseConstructedConfig parses the configuration of constructed inventory in YAML
+func ParseConstructedConfig(r io.Reader) (*ConstructedConfig, error) {
+	config := &ConstructedConfig{}
+	if err := yaml.NewDecoder(r).Decode(config); err != nil && err != io.EOF {
+		return nil, err
+	}
+	switch config.Plugin {
+	case "", "constructed", "ansible.builtin.constructed":
+		return config, nil
+	}
+	return nil, fmt.Errorf("unsupported inventory plugin: %s", config.Plugin)
+}
+
+// compiledConstructedConfig contains compiled expressions of ConstructedConfig
+type compiledConstructedConfig struct {
+	compose     []*expression
+	groups      []*expression
+	keyedGroups []*expression
+}
+
+// Construct creates groups and variables as defined by the configuration of constructed inventory.
+// It should be called after variables are loaded, as the expressions usually refer to them.
+//
+// Composed variables are added to host inventory vars and the inventory is reconciled.
+// On errors in strict mode the inventory is left unchanged.
+func (inventory *InventoryData) Construct(config *ConstructedConfig) error {
+	compiled, err := config.compile()
+	if err != nil {
+		return err
+	}
+
+	// all hosts are evaluated before changing any, so that errors in strict mode leave the inventory unchanged
+	results := make([]constructedHost, 0, len(inventory.Hosts))
+	for _, host := range HostMapListValues(inventory.Hosts) {
+		result, err := evalConstructedHost(host, config, compiled)
+		if err != nil {
+			return err
+		}
+		results = append(results, result)
+	}
+	for _, result := range results {
+		inventory.applyConstructedHost(result)
+	}
+	inventory.Reconcile()
+	return nil
+}
+
+// constructedHost is the result of constructed expressions for a host, to be applied after all hosts are evaluated
+type constructedHost struct {
+	host *Host
+	// vars are composed variables in order of definition
+	vars []constructedVar
+	// groups are groups to add the host to, with parent groups for keyed groups
+	groups []constructedGroup
+}
+
+type constructedVar struct {
+	name  string
+	value string
+}
+
+type constructedGroup struct {
+	name   string
+	parent string
+}
+
+func (config *ConstructedConfig) compile() (*compiledConstructedConfig, error) {
+	compiled := &compiledConstructedConfig{}
+	for _, compose := range config.Compose {
+		expr, err := compileExpression(compose.Expression)
+		if err != nil {
+			return nil, fmt.Errorf("compose %s: %w", compose.Name, err)
+		}
+		compiled.compose = append(compiled.compose, expr)
+	}
+	for _, group := range config.Groups {
+		expr, err := compileExpression(group.Expression)
+		if err != nil {
+			return nil, fmt.Errorf("group %s: %w", group.Name, err)
+		}
+		compiled.groups = append(compiled.groups, expr)
+	}
+	for index, keyed := range config.KeyedGroups {
+		if keyed.Key == "" {
+			return nil, fmt.Errorf("keyed_groups[%d]: key is missing", index)
+		}
+		if keyed.DefaultValue != nil && keyed.TrailingSeparator != nil {
+			return nil, fmt.Errorf("keyed_groups[%d]: default_value and trailing_separator cannot be used together", index)
+		}
+		expr, err := compileExpression(keyed.Key)
+		if err != nil {
+			return nil, fmt.Errorf("keyed_groups[%d]: %w", index, err)
+		}
+		compiled.keyedGroups = append(compiled.keyedGroups, expr)
+	}
+	return compiled, nil
+}
+
+func evalConstructedHost(host *Host, config *ConstructedConfig, compiled *compiledConstructedConfig) (constructedHost, error) {
+	result := constructedHost{host: host}
+	vars := copyStringMap(host.Vars)
+	env := chainEnv{host.magicExprEnv(), varsEnv(vars)}
+
+	for index, expr := range compiled.compose {
+		name := config.Compose[index].Name
+		value, err := expr.eval(env)
+		if err == nil {
+			var str string
+			if str, err = formatVarValue(value); err == nil {
+				vars[name] = str
+				result.vars = append(result.vars, constructedVar{name: name, value: str})
+				continue
+			}
+		}
+		if config.Strict {
+			return result, fmt.Errorf("host %s: compose %s: %w", host.Name, name, err)
+		}
+	}
+
+	for index, expr := range compiled.groups {
+		name := config.Groups[index].Name
+		matched, err := expr.evalBool(env)
+		if err != nil {
+			if config.Strict {
+				return result, fmt.Errorf("host %s: group %s: %w", host.Name, name, err)
+			}
+			continue
+		}
+		if matched {
+			result.groups = append(result.groups, constructedGroup{name: sanitizeGroupName(name)})
+		}
+	}
+
+	for index, expr := range compiled.keyedGroups {
+		keyed := config.KeyedGroups[index]
+		value, err := expr.eval(env)
+		if err != nil {
+			if config.Strict {
+				return result, fmt.Errorf("host %s: keyed group %s: %w", host.Name, keyed.Key, err)
+			}
+			continue
+		}
+		names, err := keyed.groupNames(value, config.LeadingSeparator == nil || *config.LeadingSeparator)
+		if err != nil {
+			if config.Strict {
+				return result, fmt.Errorf("host %s: keyed group %s: %w", host.Name, keyed.Key, err)
+			}
+			continue
+		}
+		for _, name := range names {
+			group := constructedGroup{name: name}
+			if keyed.ParentGroup != "" {
+				group.parent = sanitizeGroupName(keyed.ParentGroup)
+			}
+			result.groups = append(result.groups, group)
+		}
+	}
+	return result, nil
+}
+
+// applyConstructedHost adds composed variables and groups to the host
+func (inventory *InventoryData) applyConstructedHost(result constructedHost) {
+	for _, value := range result.vars {
+		result.host.InventoryVars[value.name] = value.value
+	}
+	for _, constructed := range result.groups {
+		group := inventory.getOrCreateGroup(constructed.name)
+		inventory.addHostToGroup(result.host, group)
+		if constructed.parent != "" {
+			parent := inventory.getOrCreateGroup(constructed.parent)
+			group.DirectParents[parent.Name] = parent
+		}
+	}
+}
+
+// groupNames makes names of keyed groups from the result of key expression
+func (keyed KeyedGroup) groupNames(key any, leadingSeparator bool) ([]string, error) {
+	separator := "_"
+	if keyed.Separator != nil {
+		separator = *keyed.Separator
+	}
+	values := make([]string, 0)
+	emptyValue := func(value string) (string, bool) {
+		if value != "" {
+			return value, true
+		}
+		if keyed.DefaultValue != nil {
+			return *keyed.DefaultValue, true
+		}
+		return "", keyed.TrailingSeparator == nil || *keyed.TrailingSeparator
+	}
+
+	switch k := decodeIfString(key).(type) {
+	case []any:
+		for _, item := range k {
+			value, err := formatVarValue(item)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+	case map[string]any:
+		names := make([]string, 0, len(k))
+		for name := range k {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			value, err := formatVarValue(k[name])
+			if err != nil {
+				return nil, err
+			}
+			if value, ok := emptyValue(value); ok {
+				values = append(values, name+separator+value)
+			} else {
+				values = append(values, name)
+			}
+		}
+	case nil:
+		return nil, fmt.Errorf("invalid group name format, expected a string or a list of them or dictionary")
+	default:
+		value, err := formatVarValue(k)
+		if err != nil {
+			return nil, err
+		}
+		if value, ok := emptyValue(value); ok {
+			values = append(values, value)
+		} else {
+			// no trailing separator: the group is named after prefix only
+			return []string{sanitizeGroupName(keyed.Prefix)}, nil
+		}
+	}
+
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		sep := separator
+		if keyed.Prefix == "" && !leadingSeparator {
+			sep = ""
+		}
+		result = append(result, sanitizeGroupName(keyed.Prefix+sep+value))
+	}
+	return result, nil
+}
+
+// addHostToGroup makes the host a direct member of the group, removing it from "ungrouped"
+func (inventory *InventoryData) addHostToGroup(host *Host, group *Group) {
+	host.DirectGroups[group.Name] = group
+	if group.Name != "ungrouped" {
+		delete(host.DirectGroups, "ungrouped")
+	}
+}
+
+var invalidGroupCharsRegex = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// sanitizeGroupName replaces characters not allowed in Ansible group names with underscores
+func sanitizeGroupName(name string) string {
+	return invalidGroupCharsRegex.ReplaceAllString(name, "_")
+}
+
+// magicExprEnv provides a few Ansible magic variables to expressions, see MagicVars
+func (host *Host) magicExprEnv() exprEnv {
+	names := host.magicGroupNames()
+	groupNames := make([]any, len(names))
+	for i, name := range names {
+		groupNames[i] = name
+	}
+	return mapEnv{
+		"inventory_hostname":       host.Name,
+		"inventory_hostname_short": host.shortName(),
+		"group_names":              groupNames,
+	}
+}
@@ -0,0 +1,132 @@
+package aini
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const constructedConfigYAML = `
+plugin: ansible.builtin.constructed
+strict: false
+compose:
+  ansible_port: port_base | int + 1
+  fqdn: inventory_hostname ~ '.' ~ domain
+groups:
+  prod_web: "env == 'prod' and 'web' in group_names"
+  high-port: ansible_port > 2000
+keyed_groups:
+  - key: datacenter
+    prefix: dc
+  - key: tags
+    prefix: tag
+    parent_group: tagged
+  - key: labels
+  - key: role
+    prefix: role
+    default_value: none
+`
+
+func TestConstruct(t *testing.T) {
+	v := parseString(t, `
+	[web]
+	web1 env=prod datacenter=eu-1 port_base=2221 domain=example.com
+	web2 env=staging datacenter=us-1
+	[db]
+	db1 env=prod role=""
+	[all:vars]
+	port_base=21
+	`)
+	v.Hosts["web1"].FileVars["tags"] = `["a","b"]`
+	v.Hosts["web1"].FileVars["labels"] = `{"tier":"front"}`
+	v.Reconcile()
+
+	config, err := ParseConstructedConfig(strings.NewReader(constructedConfigYAML))
+	assert.Nil(t, err)
+	assert.Equal(t, "ansible_port", config.Compose[0].Name)
+	assert.Equal(t, "fqdn", config.Compose[1].Name)
+
+	assert.Nil(t, v.Construct(config))
+
+	assert.Equal(t, "2222", v.Hosts["web1"].Vars["ansible_port"])
+	assert.Equal(t, "22", v.Hosts["web2"].Vars["ansible_port"])
+	assert.Equal(t, "web1.example.com", v.Hosts["web1"].Vars["fqdn"])
+	assert.NotContains(t, v.Hosts["web2"].Vars, "fqdn")
+
+	assert.Contains(t, v.Groups["prod_web"].Hosts, "web1")
+	assert.Len(t, v.Groups["prod_web"].Hosts, 1)
+	assert.Contains(t, v.Groups["high_port"].Hosts, "web1")
+	assert.Len(t, v.Groups["high_port"].Hosts, 1)
+
+	assert.Contains(t, v.Groups["dc_eu_1"].Hosts, "web1")
+	assert.Contains(t, v.Groups["dc_us_1"].Hosts, "web2")
+	assert.Contains(t, v.Groups["tag_a"].Hosts, "web1")
+	assert.Contains(t, v.Groups["tag_b"].Hosts, "web1")
+	assert.Contains(t, v.Groups["tag_a"].Parents, "tagged")
+	assert.Contains(t, v.Groups["tagged"].Hosts, "web1")
+	assert.Contains(t, v.Groups["_tier_front"].Hosts, "web1")
+	assert.Contains(t, v.Groups["role_none"].Hosts, "db1")
+}
+
+func TestConstructStrict(t *testing.T) {
+	v := parseString(t, `
+	web1 env=prod
+	web2
+	`)
+
+	config, err := ParseConstructedConfig(strings.NewReader(`
+strict: true
+compose:
+  env_upper: env | upper
+groups:
+  prod: env == 'prod'
+`))
+	assert.Nil(t, err)
+	err = v.Construct(config)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "web2")
+	}
+	// nothing is applied to hosts evaluated before the failing one
+	assert.NotContains(t, v.Hosts["web1"].InventoryVars, "env_upper")
+	assert.NotContains(t, v.Hosts["web1"].Vars, "env_upper")
+	assert.NotContains(t, v.Groups, "prod")
+
+	config.Strict = false
+	assert.Nil(t, v.Construct(config))
+	assert.Contains(t, v.Groups["prod"].Hosts, "web1")
+	assert.NotContains(t, v.Groups["ungrouped"].Hosts, "web1")
+	assert.Contains(t, v.Groups["ungrouped"].Hosts, "web2")
+
+	_, err = ParseConstructedConfig(strings.NewReader(`plugin: aws_ec2`))
+	assert.NotNil(t, err)
+
+	_, err = ParseConstructedConfig(strings.NewReader(`groups: [a, b]`))
+	assert.NotNil(t, err)
+
+	assert.NotNil(t, v.Construct(&ConstructedConfig{Groups: NamedExpressions{{Name: "x", Expression: "a =="}}}))
+}
+
+func TestConstructNullKey(t *testing.T) {
+	v := parseString(t, `
+	web1 role=web
+	web2
+	`)
+
+	config, err := ParseConstructedConfig(strings.NewReader(`
+keyed_groups:
+  - key: role | default(none)
+    prefix: role
+`))
+	assert.Nil(t, err)
+	assert.Nil(t, v.Construct(config))
+	assert.Contains(t, v.Groups["role_web"].Hosts, "web1")
+	assert.Contains(t, v.Groups["ungrouped"].Hosts, "web2")
+	assert.Len(t, v.Hosts["web2"].Groups, 2)
+
+	config.Strict = true
+	err = v.Construct(config)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "web2")
+	}
+}
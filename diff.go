@@ -0,0 +1,99 @@
+package aini
+
+import (
+	"strings"
+
+	"golang.org/x/exp/maps"
+)
+
+// InventoryDiff summarizes changes of hosts and groups between two versions of an inventory.
+// All lists are names in lexical order.
+type InventoryDiff struct {
+	AddedHosts   []string
+	RemovedHosts []string
+	// ChangedHosts are hosts whose port, variables or groups changed
+	ChangedHosts  []string
+	AddedGroups   []string
+	RemovedGroups []string
+	// ChangedGroups are groups whose variables, hosts, parents or children changed
+	ChangedGroups []string
+}
+
+// Diff compares the inventory with a newer version of it
+func (inventory *InventoryData) Diff(newer *InventoryData) InventoryDiff {
+	diff := InventoryDiff{}
+	diff.AddedHosts, diff.RemovedHosts, diff.ChangedHosts = diffNamed(inventory.Hosts, newer.Hosts, hostChanged)
+	diff.AddedGroups, diff.RemovedGroups, diff.ChangedGroups = diffNamed(inventory.Groups, newer.Groups, groupChanged)
+	return diff
+}
+
+// IsEmpty checks whether nothing changed
+func (diff InventoryDiff) IsEmpty() bool {
+	return len(diff.AddedHosts)+len(diff.RemovedHosts)+len(diff.ChangedHosts)+
+		len(diff.AddedGroups)+len(diff.RemovedGroups)+len(diff.ChangedGroups) == 0
+}
+
+func (diff InventoryDiff) String() string {
+	if diff.IsEmpty() {
+		return "no changes"
+	}
+	parts := make([]string, 0, 6)
+	for _, change := range []struct {
+		title string
+		names []string
+	}{
+		{"hosts added", diff.AddedHosts},
+		{"hosts removed", diff.RemovedHosts},
+		{"hosts changed", diff.ChangedHosts},
+		{"groups added", diff.AddedGroups},
+		{"groups removed", diff.RemovedGroups},
+		{"groups changed", diff.ChangedGroups},
+	} {
+		if len(change.names) > 0 {
+			parts = append(parts, change.title+": "+strings.Join(change.names, ", "))
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// diffNamed returns sorted names of added, removed and changed items
+func diffNamed[V any](older map[string]V, newer map[string]V, changed func(a V, b V) bool) ([]string, []string, []string) {
+	var added, removed, modified []string
+	for _, name := range sortedKeys(newer) {
+		previous, ok := older[name]
+		switch {
+		case !ok:
+			added = append(added, name)
+		case changed(previous, newer[name]):
+			modified = append(modified, name)
+		}
+	}
+	for _, name := range sortedKeys(older) {
+		if _, ok := newer[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	return added, removed, modified
+}
+
+func hostChanged(a *Host, b *Host) bool {
+	return a.Port != b.Port || !maps.Equal(a.Vars, b.Vars) || !sameKeys(a.Groups, b.Groups)
+}
+
+func groupChanged(a *Group, b *Group) bool {
+	return !maps.Equal(a.Vars, b.Vars) || !sameKeys(a.Hosts, b.Hosts) ||
+		!sameKeys(a.Parents, b.Parents) || !sameKeys(a.Children, b.Children)
+}
+
+// sameKeys checks whether both maps have the same keys
+func sameKeys[V any](a map[string]V, b map[string]V) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key := range a {
+		if _, ok := b[key]; !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,46 @@
+package aini
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	older := parseString(t, `
+	[web]
+	web1 env=prod
+	web2
+	[db]
+	db1
+	db2
+	`)
+	newer := parseString(t, `
+	[web]
+	web1 env=dev
+	web2
+	web3
+	[db]
+	db1:2222
+	[cache]
+	db2
+	`)
+	diff := older.Diff(newer)
+	assert.Equal(t, InventoryDiff{
+		AddedHosts:    []string{"web3"},
+		ChangedHosts:  []string{"db1", "db2", "web1"},
+		RemovedHosts:  nil,
+		AddedGroups:   []string{"cache"},
+		ChangedGroups: []string{"all", "db", "web"},
+	}, diff)
+	assert.False(t, diff.IsEmpty())
+	assert.Equal(t, "hosts added: web3; hosts changed: db1, db2, web1; groups added: cache; groups changed: all, db, web", diff.String())
+
+	diff = newer.Diff(older)
+	assert.Equal(t, []string{"web3"}, diff.RemovedHosts)
+	assert.Equal(t, []string{"cache"}, diff.RemovedGroups)
+
+	same := older.Diff(older.Clone())
+	assert.True(t, same.IsEmpty())
+	assert.Equal(t, "no changes", same.String())
+}
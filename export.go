@@ -0,0 +1,220 @@
+package aini
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// EtcHostsOptions configures export of /etc/hosts entries
+type EtcHostsOptions struct {
+	// AliasesVar is the host variable containing additional names of the host, separated by spaces or commas
+	AliasesVar string
+	// ShortNames adds the first component of dotted hostnames as an alias, e.g. "web1" for "web1.example.com"
+	ShortNames bool
+}
+
+// WriteHostList writes names of hosts matched by the given Ansible host patterns, one per line in lexical order
+func (inventory *InventoryData) WriteHostList(w io.Writer, patterns string) error {
+	hosts, err := inventory.MatchHostsByPatterns(patterns)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	for _, host := range HostMapListValues(hosts) {
+		fmt.Fprintln(bw, host.Name)
+	}
+	return bw.Flush()
+}
+
+// WriteEtcHosts writes /etc/hosts entries of hosts matched by the given Ansible host patterns
+//
+// The address is taken from `ansible_host` (or its aliases). Hosts without an IP address there are skipped.
+func (inventory *InventoryData) WriteEtcHosts(w io.Writer, patterns string, options EtcHostsOptions) error {
+	hosts, err := inventory.MatchHostsByPatterns(patterns)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	for _, host := range HostMapListValues(hosts) {
+		// only the address is needed, so invalid ports or other connection vars don't matter
+		address := resolveStringParam(host.Vars, connectionHostVars, host.Name).Value
+		if net.ParseIP(address) == nil {
+			continue
+		}
+		names := []string{host.Name}
+		if short, _, found := strings.Cut(host.Name, "."); found && options.ShortNames && net.ParseIP(host.Name) == nil {
+			names = append(names, short)
+		}
+		if options.AliasesVar != "" {
+			names = append(names, strings.FieldsFunc(host.Vars[options.AliasesVar], func(r rune) bool {
+				return r == ',' || r == ' ' || r == '\t'
+			})...)
+		}
+		fmt.Fprintf(bw, "%s\t%s\n", address, strings.Join(names, " "))
+	}
+	return bw.Flush()
+}
+
+// WriteNodesetGroups writes a ClusterShell groups file mapping each non-empty group to the folded nodeset of its hosts,
+// e.g. `web: web[01-10]`
+func (inventory *InventoryData) WriteNodesetGroups(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, group := range GroupMapListValues(inventory.Groups) {
+		if len(group.Hosts) == 0 {
+			continue
+		}
+		hostNames := make([]string, 0, len(group.Hosts))
+		for name := range group.Hosts {
+			hostNames = append(hostNames, name)
+		}
+		fmt.Fprintf(bw, "%s: %s\n", group.Name, FoldNodeset(hostNames))
+	}
+	return bw.Flush()
+}
+
+// FoldHostNames folds host names into Ansible host patterns with numeric ranges, the reverse of host pattern expansion.
+//
+// e.g. [web01, web02, web03, db1] => [db1, web[01:03]]
+func FoldHostNames(names []string) []string {
+	result := make([]string, 0)
+	for _, r := range foldHostRanges(names) {
+		if r.begin == r.end {
+			result = append(result, r.format(r.begin))
+		} else {
+			result = append(result, fmt.Sprintf("%s[%s:%s]%s", r.prefix, r.formatNumber(r.begin), r.formatNumber(r.end), r.suffix))
+		}
+	}
+	return result
+}
+
+// FoldNodeset folds host names into a ClusterShell/pdsh nodeset
+//
+// e.g. [web01, web02, web03, web05, db1] => "db1,web[01-03,05]"
+func FoldNodeset(names []string) string {
+	parts := make([]string, 0)
+	ranges := foldHostRanges(names)
+	for i := 0; i < len(ranges); {
+		r := ranges[i]
+		j := i + 1
+		for j < len(ranges) && ranges[j].isNumbered && r.isNumbered && ranges[j].prefix == r.prefix && ranges[j].suffix == r.suffix {
+			j++
+		}
+		if j == i+1 && r.begin == r.end {
+			parts = append(parts, r.format(r.begin))
+		} else {
+			rangeParts := make([]string, 0, j-i)
+			for _, rr := range ranges[i:j] {
+				if rr.begin == rr.end {
+					rangeParts = append(rangeParts, rr.formatNumber(rr.begin))
+				} else {
+					rangeParts = append(rangeParts, rr.formatNumber(rr.begin)+"-"+rr.formatNumber(rr.end))
+				}
+			}
+			parts = append(parts, fmt.Sprintf("%s[%s]%s", r.prefix, strings.Join(rangeParts, ","), r.suffix))
+		}
+		i = j
+	}
+	return strings.Join(parts, ",")
+}
+
+// hostRange is a range of host names differing only by the number in the last numeric part
+type hostRange struct {
+	prefix     string
+	suffix     string
+	width      int // zero-padded width of numbers
+	begin      int
+	end        int
+	isNumbered bool
+}
+
+func (r hostRange) formatNumber(n int) string {
+	return fmt.Sprintf("%0*d", r.width, n)
+}
+
+func (r hostRange) format(n int) string {
+	if !r.isNumbered {
+		return r.prefix
+	}
+	return r.prefix + r.formatNumber(n) + r.suffix
+}
+
+// foldHostRanges groups host names into consecutive ranges sorted by prefix, suffix and numbers
+func foldHostRanges(names []string) []hostRange {
+	type numberedName struct {
+		prefix string
+		digits string
+		suffix string
+		number int
+	}
+	parsed := make([]numberedName, 0, len(names))
+	seen := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		prefix, digits, suffix := splitLastNumber(name)
+		number, err := strconv.Atoi(digits)
+		if digits == "" || err != nil {
+			parsed = append(parsed, numberedName{prefix: name, number: -1})
+			continue
+		}
+		parsed = append(parsed, numberedName{prefix, digits, suffix, number})
+	}
+	sort.Slice(parsed, func(i, j int) bool {
+		a, b := parsed[i], parsed[j]
+		if a.prefix != b.prefix {
+			return a.prefix < b.prefix
+		}
+		if a.suffix != b.suffix {
+			return a.suffix < b.suffix
+		}
+		if a.number != b.number {
+			return a.number < b.number
+		}
+		return len(a.digits) > len(b.digits)
+	})
+
+	result := make([]hostRange, 0)
+	for _, n := range parsed {
+		if n.number < 0 {
+			result = append(result, hostRange{prefix: n.prefix})
+			continue
+		}
+		if len(result) > 0 {
+			last := &result[len(result)-1]
+			if last.isNumbered && last.prefix == n.prefix && last.suffix == n.suffix &&
+				last.end+1 == n.number && last.formatNumber(n.number) == n.digits {
+				last.end = n.number
+				continue
+			}
+		}
+		result = append(result, hostRange{
+			prefix:     n.prefix,
+			suffix:     n.suffix,
+			width:      len(n.digits),
+			begin:      n.number,
+			end:        n.number,
+			isNumbered: true,
+		})
+	}
+	return result
+}
+
+// splitLastNumber splits `web01-eu` into `web`, `01` and `-eu`
+func splitLastNumber(name string) (string, string, string) {
+	end := strings.LastIndexAny(name, "0123456789")
+	if end < 0 {
+		return name, "", ""
+	}
+	begin := end
+	for begin > 0 && name[begin-1] >= '0' && name[begin-1] <= '9' {
+		begin--
+	}
+	return name[:begin], name[begin : end+1], name[end+1:]
+}
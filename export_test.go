@@ -0,0 +1,51 @@
+package aini
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFoldHostNames(t *testing.T) {
+	assert.Equal(t, []string{"db1", "web[01:03]", "web05"}, FoldHostNames([]string{"web02", "web01", "db1", "web05", "web03", "web01"}))
+	assert.Equal(t, []string{"node[8:11]"}, FoldHostNames([]string{"node8", "node9", "node10", "node11"}))
+	assert.Equal(t, []string{"node01", "node1"}, FoldHostNames([]string{"node01", "node1"}))
+	assert.Equal(t, []string{"gateway", "rack1-node[1:2].eu"}, FoldHostNames([]string{"rack1-node2.eu", "gateway", "rack1-node1.eu"}))
+
+	for _, pattern := range []string{"host-[001:015]-web", "web[8:12]"} {
+		names, err := expandHostPattern(pattern)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{pattern}, FoldHostNames(names))
+	}
+}
+
+func TestFoldNodeset(t *testing.T) {
+	assert.Equal(t, "db1,web[01-03,05]", FoldNodeset([]string{"web02", "web01", "db1", "web05", "web03"}))
+	assert.Equal(t, "gateway", FoldNodeset([]string{"gateway"}))
+	assert.Equal(t, "", FoldNodeset(nil))
+}
+
+func TestExporters(t *testing.T) {
+	v := parseString(t, `
+	gateway.example.com ansible_host=10.0.0.1 aliases="gw,router"
+	[web]
+	web[01:03] ansible_host=10.0.1.1
+	web04
+	[db]
+	db1 ansible_ssh_host=10.0.2.1
+	db2 ansible_host=10.0.2.2 ansible_port=abc
+	`)
+
+	var sb strings.Builder
+	assert.Nil(t, v.WriteHostList(&sb, "web:db"))
+	assert.Equal(t, "db1\ndb2\nweb01\nweb02\nweb03\nweb04\n", sb.String())
+
+	sb.Reset()
+	assert.Nil(t, v.WriteEtcHosts(&sb, "all:!web", EtcHostsOptions{AliasesVar: "aliases", ShortNames: true}))
+	assert.Equal(t, "10.0.2.1\tdb1\n10.0.2.2\tdb2\n10.0.0.1\tgateway.example.com gateway gw router\n", sb.String())
+
+	sb.Reset()
+	assert.Nil(t, v.WriteNodesetGroups(&sb))
+	assert.Equal(t, "all: db[1-2],gateway.example.com,web[01-04]\ndb: db[1-2]\nungrouped: gateway.example.com\nweb: web[01-04]\n", sb.String())
+}
@@ -0,0 +1,1255 @@
+package aini
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expressions are a small subset of Jinja2 expressions as used in Ansible conditionals:
+//
+//   - literals: 'str', "str", 12, 1.5, true, false, none, [list, of, items]
+//   - variables with attribute and index access: var, var.key, var['key'], var[0]
+//   - comparison: ==, !=, <, <=, >, >=, in, not in, and matches, not matches for shell globs (not in Jinja2)
+//   - logic: and, or, not (also &&, ||, !)
+//   - arithmetic and concatenation: +, -, ~
+//   - filters: var | default('x'), lower, upper, int, float, string, bool, length, join(sep), replace(a, b), trim, first, last, sort
+//   - tests: var is defined, is undefined, is none, is string, is number, is match(regex), is search(regex),
+//     is version('1.2', '<')
+//
+// Variable values in inventories are strings; JSON-encoded lists and objects are decoded on access
+// and strings that look like numbers are compared as numbers. Dotted versions like "5.4.0" and number literals with
+// multi-digit fractions like 5.10 are compared by components to numbers and versions, so `kernel < 5.10` holds for "5.9".
+
+// expression is a compiled expression
+type expression struct {
+	source string
+	root   exprNode
+}
+
+// exprEnv resolves variables during evaluation
+type exprEnv interface {
+	lookup(name string) (any, bool)
+}
+
+// undefinedValue represents reference to a variable that doesn't exist
+type undefinedValue struct {
+	name string
+}
+
+// undefinedVarError is the error of using an undefined variable where a value is needed
+type undefinedVarError struct {
+	name string
+}
+
+func (err *undefinedVarError) Error() string {
+	return fmt.Sprintf("'%s' is undefined", err.name)
+}
+
+func (u undefinedValue) err() error {
+	return &undefinedVarError{name: u.name}
+}
+
+type exprNode interface {
+	eval(env exprEnv) (any, error)
+}
+
+// compileExpression parses expression source
+func compileExpression(source string) (*expression, error) {
+	tokens, err := tokenizeExpression(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression '%s': %w", source, err)
+	}
+	p := &exprParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err == nil && !p.atEnd() {
+		err = fmt.Errorf("unexpected '%s' at position %d", p.peek().text, p.peek().pos)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression '%s': %w", source, err)
+	}
+	return &expression{source: source, root: root}, nil
+}
+
+// eval evaluates the expression. Undefined variables in the result are reported as errors.
+func (expr *expression) eval(env exprEnv) (any, error) {
+	value, err := expr.root.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if u, ok := value.(undefinedValue); ok {
+		return nil, u.err()
+	}
+	return value, nil
+}
+
+// evalBool evaluates the expression and checks the truthiness of result
+func (expr *expression) evalBool(env exprEnv) (bool, error) {
+	value, err := expr.root.eval(env)
+	if err != nil {
+		return false, err
+	}
+	return truthy(value)
+}
+
+// mapEnv is an exprEnv of already decoded values
+type mapEnv map[string]any
+
+func (env mapEnv) lookup(name string) (any, bool) {
+	v, ok := env[name]
+	return v, ok
+}
+
+// varsEnv is an exprEnv over inventory string variables
+type varsEnv map[string]string
+
+func (env varsEnv) lookup(name string) (any, bool) {
+	if v, ok := env[name]; ok {
+		return decodeVarValue(v), true
+	}
+	return nil, false
+}
+
+// chainEnv looks up variables in each env in order
+type chainEnv []exprEnv
+
+func (env chainEnv) lookup(name string) (any, bool) {
+	for _, e := range env {
+		if v, ok := e.lookup(name); ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// decodeVarValue turns JSON-encoded lists and objects made by addVarsFromFile back into values
+func decodeVarValue(value string) any {
+	if strings.HasPrefix(value, "[") || strings.HasPrefix(value, "{") {
+		var decoded any
+		if err := json.Unmarshal([]byte(value), &decoded); err == nil {
+			return decoded
+		}
+	}
+	return value
+}
+
+// formatVarValue turns an evaluated value into inventory variable string, the same way as addVarsFromFile does
+func formatVarValue(value any) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case nil:
+		return "", nil
+	case undefinedValue:
+		return "", v.err()
+	default:
+		data, err := json.Marshal(v)
+		return string(data), err
+	}
+}
+
+// Tokenizer
+
+type tokenKind int
+
+const (
+	tokenIdent tokenKind = iota
+	tokenString
+	tokenNumber
+	tokenOperator
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+var exprOperators = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")", "[", "]", ",", ".", "|", "~", "+", "-"}
+
+func tokenizeExpression(source string) ([]token, error) {
+	tokens := make([]token, 0)
+	runes := []rune(source)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '_' || unicode.IsLetter(r):
+			begin := i
+			for i < len(runes) && (runes[i] == '_' || unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i])) {
+				i++
+			}
+			tokens = append(tokens, token{tokenIdent, string(runes[begin:i]), begin})
+		case unicode.IsDigit(r):
+			begin := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{tokenNumber, string(runes[begin:i]), begin})
+		case r == '\'' || r == '"':
+			begin := i
+			var sb strings.Builder
+			i++
+			for ; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				sb.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", begin)
+			}
+			i++
+			tokens = append(tokens, token{tokenString, sb.String(), begin})
+		default:
+			found := false
+			for _, op := range exprOperators {
+				if strings.HasPrefix(string(runes[i:]), op) {
+					tokens = append(tokens, token{tokenOperator, op, i})
+					i += len([]rune(op))
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("unexpected character '%c' at position %d", r, i)
+			}
+		}
+	}
+	return tokens, nil
+}
+
+// Parser
+
+type exprParser struct {
+	tokens []token
+	index  int
+}
+
+func (p *exprParser) atEnd() bool {
+	return p.index >= len(p.tokens)
+}
+
+func (p *exprParser) peek() token {
+	if p.atEnd() {
+		return token{kind: tokenOperator, text: "end of expression", pos: -1}
+	}
+	return p.tokens[p.index]
+}
+
+// accept consumes the next token if it's an operator or keyword with one of the given texts
+func (p *exprParser) accept(texts ...string) (string, bool) {
+	if p.atEnd() {
+		return "", false
+	}
+	t := p.tokens[p.index]
+	if t.kind != tokenOperator && t.kind != tokenIdent {
+		return "", false
+	}
+	for _, text := range texts {
+		if t.text == text {
+			p.index++
+			return text, true
+		}
+	}
+	return "", false
+}
+
+func (p *exprParser) expect(text string) error {
+	if _, ok := p.accept(text); !ok {
+		return fmt.Errorf("expected '%s' at position %d, got '%s'", text, p.peek().pos, p.peek().text)
+	}
+	return nil
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("or", "||"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicNode{isAnd: false, left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("and", "&&"); !ok {
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicNode{isAnd: true, left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseNot() (exprNode, error) {
+	if _, ok := p.accept("not", "!"); ok {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	left, err := p.parseConcat()
+	if err != nil {
+		return nil, err
+	}
+	if op, ok := p.accept("==", "!=", "<", "<=", ">", ">=", "in", "matches"); ok {
+		right, err := p.parseConcat()
+		if err != nil {
+			return nil, err
+		}
+		return &compareNode{op: op, left: left, right: right}, nil
+	}
+	if p.index+1 < len(p.tokens) && p.peek().text == "not" && (p.tokens[p.index+1].text == "in" || p.tokens[p.index+1].text == "matches") {
+		op := p.tokens[p.index+1].text
+		p.index += 2
+		right, err := p.parseConcat()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: &compareNode{op: op, left: left, right: right}}, nil
+	}
+	if _, ok := p.accept("is"); ok {
+		_, negated := p.accept("not")
+		if p.peek().kind != tokenIdent {
+			return nil, fmt.Errorf("expected test name at position %d", p.peek().pos)
+		}
+		name := p.tokens[p.index].text
+		p.index++
+		args, err := p.parseArgs()
+		if err != nil {
+			return nil, err
+		}
+		var node exprNode = &testNode{name: name, operand: left, args: args}
+		if negated {
+			node = &notNode{operand: node}
+		}
+		return node, nil
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseConcat() (exprNode, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("~"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		left = &arithNode{op: "~", left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseAdditive() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &arithNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if _, ok := p.accept("-"); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &arithNode{op: "-", left: &literalNode{value: float64(0)}, right: operand}, nil
+	}
+	return p.parsePostfix()
+}
+
+func (p *exprParser) parsePostfix() (exprNode, error) {
+	node, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("."); ok {
+			if p.peek().kind != tokenIdent {
+				return nil, fmt.Errorf("expected attribute name at position %d", p.peek().pos)
+			}
+			node = &indexNode{operand: node, index: &literalNode{value: p.tokens[p.index].text}}
+			p.index++
+		} else if _, ok := p.accept("["); ok {
+			index, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			node = &indexNode{operand: node, index: index}
+		} else if _, ok := p.accept("|"); ok {
+			if p.peek().kind != tokenIdent {
+				return nil, fmt.Errorf("expected filter name at position %d", p.peek().pos)
+			}
+			name := p.tokens[p.index].text
+			p.index++
+			if _, ok := exprFilters[name]; !ok {
+				return nil, fmt.Errorf("unknown filter '%s'", name)
+			}
+			args, err := p.parseArgs()
+			if err != nil {
+				return nil, err
+			}
+			node = &filterNode{name: name, operand: node, args: args}
+		} else {
+			return node, nil
+		}
+	}
+}
+
+// parseArgs parses optional argument list in parentheses
+func (p *exprParser) parseArgs() ([]exprNode, error) {
+	if _, ok := p.accept("("); !ok {
+		return nil, nil
+	}
+	return p.parseList(")")
+}
+
+func (p *exprParser) parseList(closing string) ([]exprNode, error) {
+	items := make([]exprNode, 0)
+	if _, ok := p.accept(closing); ok {
+		return items, nil
+	}
+	for {
+		item, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		if _, ok := p.accept(closing); ok {
+			return items, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	if p.atEnd() {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	t := p.tokens[p.index]
+	p.index++
+	switch t.kind {
+	case tokenString:
+		return &literalNode{value: t.text}, nil
+	case tokenNumber:
+		n, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number '%s' at position %d", t.text, t.pos)
+		}
+		return &literalNode{value: n, text: t.text}, nil
+	case tokenIdent:
+		switch t.text {
+		case "true", "True":
+			return &literalNode{value: true}, nil
+		case "false", "False":
+			return &literalNode{value: false}, nil
+		case "none", "None", "null":
+			return &literalNode{value: nil}, nil
+		case "and", "or", "not", "in", "is":
+			return nil, fmt.Errorf("unexpected '%s' at position %d", t.text, t.pos)
+		}
+		return &varNode{name: t.text}, nil
+	}
+	switch t.text {
+	case "(":
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return node, p.expect(")")
+	case "[":
+		items, err := p.parseList("]")
+		if err != nil {
+			return nil, err
+		}
+		return &listNode{items: items}, nil
+	}
+	return nil, fmt.Errorf("unexpected '%s' at position %d", t.text, t.pos)
+}
+
+// Nodes
+
+type literalNode struct {
+	value any
+	text  string // source of number literals, to compare them as versions
+}
+
+func (node *literalNode) eval(env exprEnv) (any, error) {
+	return node.value, nil
+}
+
+type varNode struct {
+	name string
+}
+
+func (node *varNode) eval(env exprEnv) (any, error) {
+	if value, ok := env.lookup(node.name); ok {
+		return value, nil
+	}
+	return undefinedValue{name: node.name}, nil
+}
+
+type listNode struct {
+	items []exprNode
+}
+
+func (node *listNode) eval(env exprEnv) (any, error) {
+	result := make([]any, 0, len(node.items))
+	for _, item := range node.items {
+		value, err := evalDefined(item, env)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, value)
+	}
+	return result, nil
+}
+
+type logicNode struct {
+	isAnd bool
+	left  exprNode
+	right exprNode
+}
+
+func (node *logicNode) eval(env exprEnv) (any, error) {
+	left, err := node.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	leftTrue, err := truthy(left)
+	if err != nil {
+		return nil, err
+	}
+	if leftTrue != node.isAnd {
+		return leftTrue, nil
+	}
+	right, err := node.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	return truthy(right)
+}
+
+type notNode struct {
+	operand exprNode
+}
+
+func (node *notNode) eval(env exprEnv) (any, error) {
+	value, err := node.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	b, err := truthy(value)
+	return !b, err
+}
+
+type compareNode struct {
+	op    string
+	left  exprNode
+	right exprNode
+}
+
+func (node *compareNode) eval(env exprEnv) (any, error) {
+	left, err := node.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	right, err := node.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch node.op {
+	case "==", "!=":
+		for _, v := range []any{left, right} {
+			if u, ok := v.(undefinedValue); ok {
+				return nil, u.err()
+			}
+		}
+	}
+	switch node.op {
+	case "==":
+		return valuesEqual(left, right), nil
+	case "!=":
+		return !valuesEqual(left, right), nil
+	case "in":
+		return valueContains(right, left)
+	case "matches":
+		return globMatches(left, right)
+	}
+	var cmp int
+	if lv, rv, ok := versionOperands(node.left, left, node.right, right); ok {
+		cmp = compareVersions(lv, rv)
+	} else if cmp, err = compareValues(left, right); err != nil {
+		return nil, err
+	}
+	switch node.op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	default:
+		return cmp >= 0, nil
+	}
+}
+
+type arithNode struct {
+	op    string
+	left  exprNode
+	right exprNode
+}
+
+func (node *arithNode) eval(env exprEnv) (any, error) {
+	left, err := evalDefined(node.left, env)
+	if err != nil {
+		return nil, err
+	}
+	right, err := evalDefined(node.right, env)
+	if err != nil {
+		return nil, err
+	}
+	if node.op == "~" {
+		l, _ := formatVarValue(left)
+		r, _ := formatVarValue(right)
+		return l + r, nil
+	}
+	ln, lok := toNumber(left)
+	rn, rok := toNumber(right)
+	if lok && rok {
+		if node.op == "+" {
+			return ln + rn, nil
+		}
+		return ln - rn, nil
+	}
+	if node.op == "+" {
+		if ls, ok := left.(string); ok {
+			if rs, ok := right.(string); ok {
+				return ls + rs, nil
+			}
+		}
+		if ll, ok := left.([]any); ok {
+			if rl, ok := right.([]any); ok {
+				return append(append([]any{}, ll...), rl...), nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("unsupported operand types for %s: %s and %s", node.op, typeName(left), typeName(right))
+}
+
+type indexNode struct {
+	operand exprNode
+	index   exprNode
+}
+
+func (node *indexNode) eval(env exprEnv) (any, error) {
+	operand, err := evalDefined(node.operand, env)
+	if err != nil {
+		return nil, err
+	}
+	index, err := evalDefined(node.index, env)
+	if err != nil {
+		return nil, err
+	}
+	if s, ok := operand.(string); ok {
+		operand = decodeVarValue(s)
+	}
+	switch o := operand.(type) {
+	case map[string]any:
+		key, _ := formatVarValue(index)
+		if value, ok := o[key]; ok {
+			return value, nil
+		}
+		return undefinedValue{name: key}, nil
+	case []any:
+		n, ok := toNumber(index)
+		if !ok || n != math.Trunc(n) {
+			return nil, fmt.Errorf("list index must be integer, not %s", typeName(index))
+		}
+		i := int(n)
+		if i < 0 {
+			i += len(o)
+		}
+		if i < 0 || i >= len(o) {
+			return undefinedValue{name: fmt.Sprintf("[%d]", int(n))}, nil
+		}
+		return o[i], nil
+	}
+	return nil, fmt.Errorf("%s has no attributes or items", typeName(operand))
+}
+
+type filterNode struct {
+	name    string
+	operand exprNode
+	args    []exprNode
+}
+
+func (node *filterNode) eval(env exprEnv) (any, error) {
+	operand, err := node.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	args := make([]any, 0, len(node.args))
+	for _, arg := range node.args {
+		value, err := evalDefined(arg, env)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, value)
+	}
+	if _, ok := operand.(undefinedValue); ok && node.name != "default" && node.name != "d" {
+		return operand, nil
+	}
+	return exprFilters[node.name](operand, args)
+}
+
+type testNode struct {
+	name    string
+	operand exprNode
+	args    []exprNode
+}
+
+func (node *testNode) eval(env exprEnv) (any, error) {
+	operand, err := node.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	_, undefined := operand.(undefinedValue)
+	switch node.name {
+	case "defined":
+		return !undefined, nil
+	case "undefined":
+		return undefined, nil
+	}
+	if undefined {
+		return nil, operand.(undefinedValue).err()
+	}
+	switch node.name {
+	case "none":
+		return operand == nil, nil
+	case "string":
+		_, ok := operand.(string)
+		return ok, nil
+	case "number":
+		_, ok := operand.(float64)
+		return ok, nil
+	case "match", "search":
+		if len(node.args) != 1 {
+			return nil, fmt.Errorf("test %s requires one argument", node.name)
+		}
+		arg, err := evalDefined(node.args[0], env)
+		if err != nil {
+			return nil, err
+		}
+		pattern, _ := formatVarValue(arg)
+		if node.name == "match" {
+			pattern = "^(?:" + pattern + ")"
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		s, _ := formatVarValue(operand)
+		return re.MatchString(s), nil
+	case "version":
+		return evalVersionTest(operand, node.args, env)
+	}
+	return nil, fmt.Errorf("unknown test '%s'", node.name)
+}
+
+// evalVersionTest compares versions as Ansible's `version` test with the default loose version type
+func evalVersionTest(operand any, args []exprNode, env exprEnv) (any, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, fmt.Errorf("test version requires one or two arguments")
+	}
+	values := make([]string, len(args))
+	for i, arg := range args {
+		value, err := evalDefined(arg, env)
+		if err != nil {
+			return nil, err
+		}
+		values[i], _ = formatVarValue(value)
+	}
+	operator := "eq"
+	if len(values) == 2 {
+		operator = values[1]
+	}
+	version, _ := formatVarValue(operand)
+	cmp := compareVersions(version, values[0])
+	switch operator {
+	case "<", "lt":
+		return cmp < 0, nil
+	case "<=", "le":
+		return cmp <= 0, nil
+	case ">", "gt":
+		return cmp > 0, nil
+	case ">=", "ge":
+		return cmp >= 0, nil
+	case "==", "=", "eq":
+		return cmp == 0, nil
+	case "!=", "<>", "ne":
+		return cmp != 0, nil
+	}
+	return nil, fmt.Errorf("invalid version operator '%s'", operator)
+}
+
+var versionComponentRegex = regexp.MustCompile(`\d+|[A-Za-z]+`)
+
+// compareVersions compares versions by components, numbers numerically and the rest lexically with numbers first
+func compareVersions(left, right string) int {
+	lc := versionComponentRegex.FindAllString(left, -1)
+	rc := versionComponentRegex.FindAllString(right, -1)
+	for i := 0; i < len(lc) && i < len(rc); i++ {
+		ln, lerr := strconv.Atoi(lc[i])
+		rn, rerr := strconv.Atoi(rc[i])
+		switch {
+		case lerr == nil && rerr == nil:
+			if ln != rn {
+				if ln < rn {
+					return -1
+				}
+				return 1
+			}
+		case lerr == nil:
+			return -1
+		case rerr == nil:
+			return 1
+		default:
+			if c := strings.Compare(lc[i], rc[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	switch {
+	case len(lc) < len(rc):
+		return -1
+	case len(lc) > len(rc):
+		return 1
+	}
+	return 0
+}
+
+// evalDefined evaluates a node and reports an error if the result is undefined
+func evalDefined(node exprNode, env exprEnv) (any, error) {
+	value, err := node.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if u, ok := value.(undefinedValue); ok {
+		return nil, u.err()
+	}
+	return value, nil
+}
+
+// Filters
+
+var exprFilters map[string]func(value any, args []any) (any, error)
+
+func init() {
+	defaultFilter := func(value any, args []any) (any, error) {
+		if _, ok := value.(undefinedValue); ok {
+			if len(args) == 0 {
+				return "", nil
+			}
+			return args[0], nil
+		}
+		return value, nil
+	}
+	stringFilter := func(fn func(string) string) func(value any, args []any) (any, error) {
+		return func(value any, args []any) (any, error) {
+			s, err := formatVarValue(value)
+			return fn(s), err
+		}
+	}
+	exprFilters = map[string]func(value any, args []any) (any, error){
+		"default": defaultFilter,
+		"d":       defaultFilter,
+		"lower":   stringFilter(strings.ToLower),
+		"upper":   stringFilter(strings.ToUpper),
+		"trim":    stringFilter(strings.TrimSpace),
+		"string":  stringFilter(func(s string) string { return s }),
+		"int": func(value any, args []any) (any, error) {
+			n, ok := toNumber(value)
+			if !ok {
+				return float64(0), nil
+			}
+			return math.Trunc(n), nil
+		},
+		"float": func(value any, args []any) (any, error) {
+			n, _ := toNumber(value)
+			return n, nil
+		},
+		"bool": func(value any, args []any) (any, error) {
+			if b, ok := value.(bool); ok {
+				return b, nil
+			}
+			s, _ := formatVarValue(value)
+			b, err := parseBool(s)
+			return b && err == nil, nil
+		},
+		"length": func(value any, args []any) (any, error) {
+			switch v := decodeIfString(value).(type) {
+			case []any:
+				return float64(len(v)), nil
+			case map[string]any:
+				return float64(len(v)), nil
+			case string:
+				return float64(len([]rune(v))), nil
+			}
+			return nil, fmt.Errorf("%s has no length", typeName(value))
+		},
+		"join": func(value any, args []any) (any, error) {
+			list, ok := decodeIfString(value).([]any)
+			if !ok {
+				return nil, fmt.Errorf("join requires a list, not %s", typeName(value))
+			}
+			sep := ""
+			if len(args) > 0 {
+				sep, _ = formatVarValue(args[0])
+			}
+			parts := make([]string, 0, len(list))
+			for _, item := range list {
+				s, _ := formatVarValue(item)
+				parts = append(parts, s)
+			}
+			return strings.Join(parts, sep), nil
+		},
+		"replace": func(value any, args []any) (any, error) {
+			if len(args) != 2 {
+				return nil, fmt.Errorf("replace requires two arguments")
+			}
+			s, _ := formatVarValue(value)
+			old, _ := formatVarValue(args[0])
+			new, _ := formatVarValue(args[1])
+			return strings.ReplaceAll(s, old, new), nil
+		},
+		"first": func(value any, args []any) (any, error) {
+			list, ok := decodeIfString(value).([]any)
+			if !ok || len(list) == 0 {
+				return undefinedValue{name: "first"}, nil
+			}
+			return list[0], nil
+		},
+		"last": func(value any, args []any) (any, error) {
+			list, ok := decodeIfString(value).([]any)
+			if !ok || len(list) == 0 {
+				return undefinedValue{name: "last"}, nil
+			}
+			return list[len(list)-1], nil
+		},
+		"sort": func(value any, args []any) (any, error) {
+			list, ok := decodeIfString(value).([]any)
+			if !ok {
+				return nil, fmt.Errorf("sort requires a list, not %s", typeName(value))
+			}
+			sorted := append([]any{}, list...)
+			sort.SliceStable(sorted, func(i, j int) bool {
+				cmp, _ := compareValues(sorted[i], sorted[j])
+				return cmp < 0
+			})
+			return sorted, nil
+		},
+	}
+}
+
+// Value helpers
+
+func decodeIfString(value any) any {
+	if s, ok := value.(string); ok {
+		return decodeVarValue(s)
+	}
+	return value
+}
+
+func typeName(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "none"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "bool"
+	case []any:
+		return "list"
+	case map[string]any:
+		return "dict"
+	case undefinedValue:
+		return "undefined '" + v.name + "'"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// truthy returns Jinja2 truthiness of a value
+func truthy(value any) (bool, error) {
+	switch v := value.(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return v, nil
+	case string:
+		return v != "", nil
+	case float64:
+		return v != 0, nil
+	case []any:
+		return len(v) > 0, nil
+	case map[string]any:
+		return len(v) > 0, nil
+	case undefinedValue:
+		return false, v.err()
+	}
+	return false, fmt.Errorf("unsupported value type %T", value)
+}
+
+// toNumber converts numbers and numeric strings to float64
+func toNumber(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+func valuesEqual(left, right any) bool {
+	if _, ok := left.(undefinedValue); ok {
+		return false
+	}
+	if _, ok := right.(undefinedValue); ok {
+		return false
+	}
+	_, leftIsString := left.(string)
+	_, rightIsString := right.(string)
+	if leftIsString != rightIsString {
+		// "22" == 22, as inventory variables are always strings
+		ln, lok := toNumber(left)
+		rn, rok := toNumber(right)
+		if lok && rok {
+			if _, ok := left.(bool); !ok {
+				if _, ok := right.(bool); !ok {
+					return ln == rn
+				}
+			}
+		}
+		// 'true' == true
+		if lb, ok := left.(bool); ok && rightIsString {
+			rb, err := parseBool(right.(string))
+			return err == nil && lb == rb
+		}
+		if rb, ok := right.(bool); ok && leftIsString {
+			lb, err := parseBool(left.(string))
+			return err == nil && lb == rb
+		}
+	}
+	switch l := left.(type) {
+	case []any, map[string]any:
+		lj, _ := json.Marshal(l)
+		rj, _ := json.Marshal(decodeIfString(right))
+		return string(lj) == string(rj)
+	}
+	if _, ok := right.([]any); ok {
+		return valuesEqual(right, left)
+	}
+	if _, ok := right.(map[string]any); ok {
+		return valuesEqual(right, left)
+	}
+	return left == right
+}
+
+func valueContains(container any, item any) (any, error) {
+	if u, ok := container.(undefinedValue); ok {
+		return nil, u.err()
+	}
+	switch c := decodeIfString(container).(type) {
+	case []any:
+		for _, element := range c {
+			if valuesEqual(element, item) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case map[string]any:
+		key, _ := formatVarValue(item)
+		_, ok := c[key]
+		return ok, nil
+	case string:
+		s, err := formatVarValue(item)
+		if err != nil {
+			return nil, err
+		}
+		return strings.Contains(c, s), nil
+	}
+	return nil, fmt.Errorf("'in' requires a list, dict or string, not %s", typeName(container))
+}
+
+// compareValues compares numbers (including numeric strings) numerically and other strings lexically
+func compareValues(left, right any) (int, error) {
+	for _, v := range []any{left, right} {
+		if u, ok := v.(undefinedValue); ok {
+			return 0, u.err()
+		}
+	}
+	ln, lok := toNumber(left)
+	rn, rok := toNumber(right)
+	if lok && rok {
+		switch {
+		case ln < rn:
+			return -1, nil
+		case ln > rn:
+			return 1, nil
+		}
+		return 0, nil
+	}
+	ls, lok := left.(string)
+	rs, rok := right.(string)
+	if lok && rok {
+		if isVersionString(ls) && startsWithDigit(rs) || isVersionString(rs) && startsWithDigit(ls) {
+			return compareVersions(ls, rs), nil
+		}
+		return strings.Compare(ls, rs), nil
+	}
+	return 0, &incomparableError{left: typeName(left), right: typeName(right)}
+}
+
+// incomparableError is the error of ordering values of different types, e.g. a list and a number
+type incomparableError struct {
+	left  string
+	right string
+}
+
+func (err *incomparableError) Error() string {
+	return fmt.Sprintf("cannot compare %s and %s", err.left, err.right)
+}
+
+var versionStringRegex = regexp.MustCompile(`^\d+(\.\d+)+`)
+
+// isVersionString checks whether the value is a dotted version like "5.4.0" or "5.4.0-42-generic", rather than a number
+func isVersionString(value string) bool {
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return false
+	}
+	return versionStringRegex.MatchString(value)
+}
+
+func startsWithDigit(value string) bool {
+	return value != "" && value[0] >= '0' && value[0] <= '9'
+}
+
+// versionOperands returns the operands as versions if they are to be compared by components: either is a dotted version
+// like "5.4.0", or a number literal like 5.10 with a multi-digit fraction, which as a float would be 5.1, and the other
+// is a number or a version. Number literals are taken by their source, so `kernel_version < 5.10` holds for "5.9".
+func versionOperands(leftNode exprNode, left any, rightNode exprNode, right any) (string, string, bool) {
+	ls, lok := versionOperand(leftNode, left)
+	rs, rok := versionOperand(rightNode, right)
+	if !lok || !rok {
+		return "", "", false
+	}
+	if isVersionString(ls) || isVersionString(rs) || isVersionLiteral(leftNode) || isVersionLiteral(rightNode) {
+		return ls, rs, true
+	}
+	return "", "", false
+}
+
+// versionOperand returns the operand as a possible version: the source of number literals, numbers and strings starting with digits
+func versionOperand(node exprNode, value any) (string, bool) {
+	if literal, ok := node.(*literalNode); ok && literal.text != "" {
+		return literal.text, true
+	}
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case string:
+		return v, startsWithDigit(v)
+	}
+	return "", false
+}
+
+var versionLiteralRegex = regexp.MustCompile(`^\d+\.\d\d+$`)
+
+// isVersionLiteral checks whether the node is a number literal with a multi-digit fraction like 5.10
+func isVersionLiteral(node exprNode) bool {
+	literal, ok := node.(*literalNode)
+	return ok && versionLiteralRegex.MatchString(literal.text)
+}
+
+// globMatches checks whether the string matches the shell glob pattern, as in `role matches "web*"`
+func globMatches(value, pattern any) (bool, error) {
+	for _, v := range []any{value, pattern} {
+		if u, ok := v.(undefinedValue); ok {
+			return false, u.err()
+		}
+	}
+	s, ok := value.(string)
+	if !ok {
+		return false, &incomparableError{left: typeName(value), right: typeName(pattern)}
+	}
+	p, ok := pattern.(string)
+	if !ok {
+		return false, fmt.Errorf("glob pattern must be a string, not %s", typeName(pattern))
+	}
+	matched, err := path.Match(p, s)
+	if err != nil {
+		return false, fmt.Errorf("invalid glob pattern '%s': %w", p, err)
+	}
+	return matched, nil
+}
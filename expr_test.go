@@ -0,0 +1,80 @@
+package aini
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpressions(t *testing.T) {
+	env := chainEnv{
+		mapEnv{"group_names": []any{"db", "web"}},
+		varsEnv{
+			"env":      "prod",
+			"port":     "22",
+			"enabled":  "true",
+			"kernel":   "5.4",
+			"tags":     `["a","b"]`,
+			"location": `{"dc":"eu1","rack":3}`,
+		},
+	}
+
+	cases := map[string]any{
+		`env == 'prod'`:                              true,
+		`env == "prod" && "web" in group_names`:      true,
+		`env == 'prod' and 'mail' in group_names`:    false,
+		`'mail' not in group_names`:                  true,
+		`not (env != 'prod')`:                        true,
+		`port != 22`:                                 false,
+		`port + 1`:                                   float64(23),
+		`kernel < 5.10`:                              true,
+		`kernel < 5.3`:                               false,
+		`5.9 < 5.10`:                                 true,
+		`5.10 > 5.9`:                                 true,
+		`5.10 == 5.10`:                               true,
+		`port < 21.10`:                               false,
+		`kernel < 6`:                                 true,
+		`kernel is version('5.10', '<')`:             true,
+		`'5.10.0-rc1' is version('5.10.0', 'gt')`:    true,
+		`'1.2' is version('1.2')`:                    true,
+		`'1.2' is version('1.2.0', '!=')`:            true,
+		`enabled == true`:                            true,
+		`location.dc ~ '-' ~ location['rack']`:       "eu1-3",
+		`tags[1]`:                                    "b",
+		`tags | join(',')`:                           "a,b",
+		`missing | default('x') | upper`:             "X",
+		`missing is defined`:                         false,
+		`missing is not defined or missing == 1`:     true,
+		`env is match('pr')`:                         true,
+		`env is match('rod')`:                        false,
+		`env is search('rod')`:                       true,
+		`'a' in tags and location.rack >= 3`:         true,
+		`[1, 2] | length`:                            float64(2),
+		`group_names | first`:                        "db",
+		`-port`:                                      float64(-22),
+		`env | replace('prod', 'production') | trim`: "production",
+	}
+	for source, expected := range cases {
+		expr, err := compileExpression(source)
+		if !assert.Nil(t, err, source) {
+			continue
+		}
+		value, err := expr.eval(env)
+		assert.Nil(t, err, source)
+		assert.Equal(t, expected, value, source)
+	}
+
+	for _, source := range []string{`env ==`, `(env`, `env | nosuchfilter`, `'abc`, `env # 1`, `a b`} {
+		_, err := compileExpression(source)
+		assert.NotNil(t, err, source)
+	}
+
+	for _, source := range []string{`missing`, `missing < 1`, `missing + 1`, `env is nosuchtest`, `missing.key`, `missing == 'x'`} {
+		expr, err := compileExpression(source)
+		if !assert.Nil(t, err, source) {
+			continue
+		}
+		_, err = expr.eval(env)
+		assert.NotNil(t, err, source)
+	}
+}
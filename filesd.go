@@ -0,0 +1,187 @@
+package aini
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FileSDOptions configures export of Prometheus file_sd target groups
+type FileSDOptions struct {
+	// PortVar is the host variable containing the port of the scraped target, e.g. "node_exporter_port"
+	PortVar string
+	// DefaultPort is used when PortVar is empty or not set for a host. Zero means no port in targets.
+	DefaultPort int
+	// LabelVars maps label names to host variables whose values become label values.
+	// Label names must match Prometheus syntax [a-zA-Z_][a-zA-Z0-9_]*.
+	LabelVars map[string]string
+	// GroupLabelPrefixes turns group memberships into labels, e.g. prefix "env_" makes group "env_prod" into label env="prod".
+	// Multiple matching groups are joined by comma in lexical order. Characters invalid in label names become underscores.
+	GroupLabelPrefixes []string
+}
+
+// FileSDTargetGroup is a target group in Prometheus file_sd format
+type FileSDTargetGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels,omitempty"`
+}
+
+// FileSDTargetGroups builds Prometheus file_sd target groups for hosts matched by the given Ansible host patterns.
+//
+// Target address is taken from `ansible_host` or the inventory hostname. Hosts with identical labels are put in the same group.
+// The result is ordered by labels and targets are ordered by hostnames.
+func (inventory *InventoryData) FileSDTargetGroups(patterns string, options FileSDOptions) ([]FileSDTargetGroup, error) {
+	if err := options.checkLabelNames(); err != nil {
+		return nil, err
+	}
+	hosts, err := inventory.MatchHostsByPatterns(patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	groupsByLabels := make(map[string]*FileSDTargetGroup)
+	for _, host := range HostMapListValues(hosts) {
+		target, err := host.fileSDTarget(options)
+		if err != nil {
+			return nil, err
+		}
+		labels := host.fileSDLabels(options)
+		key := labelsKey(labels)
+		if targetGroup, ok := groupsByLabels[key]; ok {
+			targetGroup.Targets = append(targetGroup.Targets, target)
+		} else {
+			groupsByLabels[key] = &FileSDTargetGroup{
+				Targets: []string{target},
+				Labels:  labels,
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(groupsByLabels))
+	for key := range groupsByLabels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := make([]FileSDTargetGroup, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, *groupsByLabels[key])
+	}
+	return result, nil
+}
+
+// WriteFileSD writes Prometheus file_sd target groups for hosts matched by the given patterns into a file.
+// The file is replaced atomically so that Prometheus never reads a partial file.
+func (inventory *InventoryData) WriteFileSD(path string, patterns string, options FileSDOptions) error {
+	targetGroups, err := inventory.FileSDTargetGroups(patterns, options)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(targetGroups, "", "    ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(data, '\n'), 0o644)
+}
+
+// checkLabelNames checks names of LabelVars against Prometheus label name syntax
+func (options FileSDOptions) checkLabelNames() error {
+	for label := range options.LabelVars {
+		if !isValidLabelName(label) {
+			return fmt.Errorf("invalid Prometheus label name '%s'", label)
+		}
+	}
+	return nil
+}
+
+// fileSDTarget resolves only the address of the host and the port from PortVar,
+// so that connection variables not used by the export cannot fail it
+func (host *Host) fileSDTarget(options FileSDOptions) (string, error) {
+	address := resolveStringParam(host.Vars, connectionHostVars, host.Name).Value
+	port := options.DefaultPort
+	if value, ok := host.Vars[options.PortVar]; ok && options.PortVar != "" {
+		var err error
+		port, err = strconv.Atoi(value)
+		if err != nil {
+			return "", fmt.Errorf("host %s: invalid %s value '%s': %w", host.Name, options.PortVar, value, err)
+		}
+	}
+	if port == 0 {
+		return address, nil
+	}
+	return net.JoinHostPort(address, strconv.Itoa(port)), nil
+}
+
+func (host *Host) fileSDLabels(options FileSDOptions) map[string]string {
+	labels := make(map[string]string)
+	for label, varName := range options.LabelVars {
+		if value, ok := host.Vars[varName]; ok {
+			labels[label] = value
+		}
+	}
+	for _, prefix := range options.GroupLabelPrefixes {
+		values := make([]string, 0)
+		for _, group := range GroupMapListValues(host.Groups) {
+			if strings.HasPrefix(group.Name, prefix) && len(group.Name) > len(prefix) {
+				values = append(values, strings.TrimPrefix(group.Name, prefix))
+			}
+		}
+		if len(values) > 0 {
+			labels[sanitizeLabelName(strings.TrimRight(prefix, "_-"))] = strings.Join(values, ",")
+		}
+	}
+	return labels
+}
+
+// isValidLabelName checks the name against Prometheus label name syntax [a-zA-Z_][a-zA-Z0-9_]*
+func isValidLabelName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// sanitizeLabelName replaces characters invalid in Prometheus label names by underscores, e.g. "dc.eu" to "dc_eu"
+func sanitizeLabelName(name string) string {
+	var sb strings.Builder
+	for i, c := range name {
+		if c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' && i > 0 {
+			sb.WriteRune(c)
+		} else if c >= '0' && c <= '9' {
+			sb.WriteString("_")
+			sb.WriteRune(c)
+		} else {
+			sb.WriteByte('_')
+		}
+	}
+	if sb.Len() == 0 {
+		return "_"
+	}
+	return sb.String()
+}
+
+// labelsKey makes a unique string key for the given set of labels
+func labelsKey(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	for _, name := range names {
+		sb.WriteString(strconv.Quote(name))
+		sb.WriteByte('=')
+		sb.WriteString(strconv.Quote(labels[name]))
+		sb.WriteByte(',')
+	}
+	return sb.String()
+}
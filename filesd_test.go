@@ -0,0 +1,90 @@
+package aini
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileSDTargetGroups(t *testing.T) {
+	v := parseString(t, `
+	[web]
+	web1 ansible_host=10.0.0.1 exporter_port=9101
+	web2
+	web3 ansible_host=fd00::3
+
+	[db]
+	db1 role=primary
+
+	[env_prod]
+	web1
+	web2
+	db1
+
+	[env_staging]
+	web3
+	`)
+
+	targetGroups, err := v.FileSDTargetGroups("web:db", FileSDOptions{
+		PortVar:            "exporter_port",
+		DefaultPort:        9100,
+		LabelVars:          map[string]string{"role": "role"},
+		GroupLabelPrefixes: []string{"env_"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []FileSDTargetGroup{
+		{Targets: []string{"10.0.0.1:9101", "web2:9100"}, Labels: map[string]string{"env": "prod"}},
+		{Targets: []string{"db1:9100"}, Labels: map[string]string{"env": "prod", "role": "primary"}},
+		{Targets: []string{"[fd00::3]:9100"}, Labels: map[string]string{"env": "staging"}},
+	}, targetGroups)
+
+	targetGroups, err = v.FileSDTargetGroups("db", FileSDOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []FileSDTargetGroup{{Targets: []string{"db1"}, Labels: map[string]string{}}}, targetGroups)
+}
+
+func TestWriteFileSD(t *testing.T) {
+	v := parseString(t, `
+	[web]
+	web1
+	web2 exporter_port=none
+	`)
+	path := filepath.Join(t.TempDir(), "targets.json")
+
+	assert.Nil(t, v.WriteFileSD(path, "web1", FileSDOptions{DefaultPort: 9100}))
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	var targetGroups []FileSDTargetGroup
+	assert.Nil(t, json.Unmarshal(data, &targetGroups))
+	assert.Equal(t, []FileSDTargetGroup{{Targets: []string{"web1:9100"}}}, targetGroups)
+
+	assert.NotNil(t, v.WriteFileSD(path, "web", FileSDOptions{PortVar: "exporter_port"}))
+	entries, err := os.ReadDir(filepath.Dir(path))
+	assert.Nil(t, err)
+	assert.Len(t, entries, 1, "temporary file must be removed")
+}
+
+func TestFileSDLabelNamesAndConnectionVars(t *testing.T) {
+	v := parseString(t, `
+	[site.dc_eu]
+	web1 ansible_port=ssh ansible_become=maybe
+	`)
+
+	targetGroups, err := v.FileSDTargetGroups("all", FileSDOptions{DefaultPort: 9100, GroupLabelPrefixes: []string{"site.dc_"}})
+	assert.Nil(t, err)
+	assert.Equal(t, []FileSDTargetGroup{{Targets: []string{"web1:9100"}, Labels: map[string]string{"site_dc": "eu"}}}, targetGroups)
+
+	_, err = v.FileSDTargetGroups("all", FileSDOptions{LabelVars: map[string]string{"become-mode": "ansible_become"}})
+	assert.Equal(t, "invalid Prometheus label name 'become-mode'", err.Error())
+}
+
+func TestSanitizeLabelName(t *testing.T) {
+	assert.Equal(t, "dc_eu", sanitizeLabelName("dc.eu"))
+	assert.Equal(t, "_1st", sanitizeLabelName("1st"))
+	assert.Equal(t, "_", sanitizeLabelName(""))
+	assert.True(t, isValidLabelName("env_1"))
+	assert.False(t, isValidLabelName("1env"))
+}
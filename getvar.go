@@ -0,0 +1,222 @@
+package aini
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrVarNotFound is wrapped by VarError for missing variables
+var ErrVarNotFound = errors.New("variable is not defined")
+
+// VarError is a variable of a host which cannot be converted by GetVar or DecodeVars
+type VarError struct {
+	Host string
+	Var  string
+	// Source tells where the value is defined, nil for missing variables
+	Source *VarSource
+	Err    error
+}
+
+func (err *VarError) Error() string {
+	if err.Source == nil {
+		return fmt.Sprintf("host %s: %s: %v", err.Host, err.Var, err.Err)
+	}
+	return fmt.Sprintf("host %s: %s (from %s): %v", err.Host, err.Var, err.Source, err.Err)
+}
+
+func (err *VarError) Unwrap() error {
+	return err.Err
+}
+
+// GetVar converts the variable of the host to T. See DecodeVars for supported types.
+// Missing variables are reported as VarError wrapping ErrVarNotFound.
+func GetVar[T any](host *Host, name string) (T, error) {
+	var result T
+	value, ok := host.Vars[name]
+	if !ok {
+		return result, host.varError(name, ErrVarNotFound)
+	}
+	if err := decodeVar(value, reflect.ValueOf(&result).Elem()); err != nil {
+		return result, host.varError(name, err)
+	}
+	return result, nil
+}
+
+// GetVarOr converts the variable of the host to T like GetVar, or returns the fallback if the variable is missing
+func GetVarOr[T any](host *Host, name string, fallback T) (T, error) {
+	if _, ok := host.Vars[name]; !ok {
+		return fallback, nil
+	}
+	return GetVar[T](host, name)
+}
+
+func (host *Host) varError(name string, err error) *VarError {
+	varErr := &VarError{Host: host.Name, Var: name, Err: err}
+	if source, ok := host.VarSource(name); ok {
+		varErr.Source = &source
+	}
+	return varErr
+}
+
+// DecodeVars sets fields of the struct pointed by target from variables of the host, by tags of fields, e.g.
+//
+//	type Backup struct {
+//		Port          int           `aini:"ansible_port,default=22"`
+//		RetentionDays int           `aini:"backup_retention_days,required"`
+//		Interval      time.Duration `aini:"backup_interval,default=24h"`
+//		Paths         []string      `aini:"backup_paths"`
+//	}
+//
+// Fields without tags are skipped and fields of missing variables are left unchanged, unless default is given.
+// Supported types are strings, bools (yes/no, true/false, etc.), numbers, time.Duration, encoding.TextUnmarshaler,
+// pointers to them, and lists, dicts and structs decoded from JSON as stored for complex values in vars files.
+//
+// All invalid variables are returned as VarError joined by errors.Join.
+func (host *Host) DecodeVars(target any) error {
+	pointer := reflect.ValueOf(target)
+	if pointer.Kind() != reflect.Pointer || pointer.IsNil() || pointer.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("DecodeVars target must be a non-nil pointer to struct, got %T", target)
+	}
+	object := pointer.Elem()
+	var errs []error
+	for i := 0; i < object.NumField(); i++ {
+		field := object.Type().Field(i)
+		tag, ok := field.Tag.Lookup("aini")
+		if !ok || tag == "-" || !field.IsExported() {
+			continue
+		}
+		parsed, err := parseVarTag(tag)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		value, found := host.Vars[parsed.name]
+		switch {
+		case found:
+			if err := decodeVar(value, object.Field(i)); err != nil {
+				errs = append(errs, host.varError(parsed.name, err))
+			}
+		case parsed.hasDefault:
+			if err := decodeVar(parsed.fallback, object.Field(i)); err != nil {
+				errs = append(errs, host.varError(parsed.name, fmt.Errorf("invalid default: %w", err)))
+			}
+		case parsed.required:
+			errs = append(errs, host.varError(parsed.name, ErrVarNotFound))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// varTag is a parsed tag of DecodeVars
+type varTag struct {
+	name       string
+	required   bool
+	fallback   string
+	hasDefault bool
+}
+
+// parseVarTag parses tags of DecodeVars as name followed by options: "required" and "default=value".
+// The default value is the rest of the tag and may contain commas.
+func parseVarTag(tag string) (varTag, error) {
+	name, options, _ := strings.Cut(tag, ",")
+	if name == "" {
+		return varTag{}, fmt.Errorf("missing variable name in tag '%s'", tag)
+	}
+	parsed := varTag{name: name}
+	for options != "" {
+		if fallback, ok := strings.CutPrefix(options, "default="); ok {
+			parsed.fallback, parsed.hasDefault = fallback, true
+			break
+		}
+		var option string
+		option, options, _ = strings.Cut(options, ",")
+		if option != "required" {
+			return varTag{}, fmt.Errorf("unknown option '%s' in tag '%s'", option, tag)
+		}
+		parsed.required = true
+	}
+	return parsed, nil
+}
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// decodeVar sets the target to the value of a variable, converted to the type of target
+func decodeVar(value string, target reflect.Value) error {
+	if target.Kind() == reflect.Pointer {
+		elem := reflect.New(target.Type().Elem())
+		if err := decodeVar(value, elem.Elem()); err != nil {
+			return err
+		}
+		target.Set(elem)
+		return nil
+	}
+	if reflect.PointerTo(target.Type()).Implements(textUnmarshalerType) {
+		return target.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+	if target.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("value '%s' is not a duration", value)
+		}
+		target.SetInt(int64(d))
+		return nil
+	}
+
+	switch target.Kind() {
+	case reflect.String:
+		target.SetString(value)
+	case reflect.Bool:
+		b, err := parseBool(value)
+		if err != nil {
+			return fmt.Errorf("value '%s' is not a bool", value)
+		}
+		target.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, target.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("value '%s' is not an int of %d bits", value, target.Type().Bits())
+		}
+		target.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(strings.TrimSpace(value), 10, target.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("value '%s' is not an unsigned int of %d bits", value, target.Type().Bits())
+		}
+		target.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(strings.TrimSpace(value), target.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("value '%s' is not a float", value)
+		}
+		target.SetFloat(n)
+	case reflect.Interface:
+		if target.NumMethod() > 0 {
+			return fmt.Errorf("unsupported type %s", target.Type())
+		}
+		// JSON-encoded lists and dicts are decoded, other values are kept as strings
+		var decoded any
+		if json.Unmarshal([]byte(value), &decoded) == nil {
+			switch decoded.(type) {
+			case []any, map[string]any:
+				target.Set(reflect.ValueOf(decoded))
+				return nil
+			}
+		}
+		target.Set(reflect.ValueOf(value))
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Struct:
+		if err := json.Unmarshal([]byte(value), target.Addr().Interface()); err != nil {
+			return fmt.Errorf("value '%s' is not a JSON %s: %w", value, target.Type(), err)
+		}
+	default:
+		return fmt.Errorf("unsupported type %s", target.Type())
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package aini
+
+import (
+	"errors"
+	"net/netip"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetVar(t *testing.T) {
+	root := writeVarsFiles(t, map[string]string{
+		"hosts":                "[db]\ndb1 backup_retention_days=14 backup_enabled=yes\n",
+		"host_vars/db1.yml":    "backup_paths: [/var/lib/db, /etc]\nlimits: {cpu: 2}\nbackup_interval: 12h\naddress: 10.0.0.1\n",
+		"group_vars/db.yml":    "backup_retention_days: 7\nreplicas: many\n",
+		"group_vars/other.yml": "",
+	})
+	v, err := ParseFile(filepath.Join(root, "hosts"))
+	assert.Nil(t, err)
+	assert.Nil(t, v.AddVars(root))
+	host := v.Hosts["db1"]
+
+	days, err := GetVar[int](host, "backup_retention_days")
+	assert.Nil(t, err)
+	assert.Equal(t, 14, days)
+	enabled, err := GetVar[bool](host, "backup_enabled")
+	assert.Nil(t, err)
+	assert.True(t, enabled)
+	paths, err := GetVar[[]string](host, "backup_paths")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"/var/lib/db", "/etc"}, paths)
+	limits, err := GetVar[map[string]int](host, "limits")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"cpu": 2}, limits)
+	interval, err := GetVar[time.Duration](host, "backup_interval")
+	assert.Nil(t, err)
+	assert.Equal(t, 12*time.Hour, interval)
+	address, err := GetVar[netip.Addr](host, "address")
+	assert.Nil(t, err)
+	assert.Equal(t, netip.MustParseAddr("10.0.0.1"), address)
+	anyPaths, err := GetVar[any](host, "backup_paths")
+	assert.Nil(t, err)
+	assert.Equal(t, []any{"/var/lib/db", "/etc"}, anyPaths)
+	pointer, err := GetVar[*int](host, "backup_retention_days")
+	assert.Nil(t, err)
+	assert.Equal(t, 14, *pointer)
+
+	_, err = GetVar[int](host, "replicas")
+	assert.Equal(t, "host db1: replicas (from group_vars/db): value 'many' is not an int of 64 bits", err.Error())
+	var varErr *VarError
+	assert.True(t, errors.As(err, &varErr))
+	assert.Equal(t, "replicas", varErr.Var)
+
+	_, err = GetVar[int8](host, "nosuchvar")
+	assert.True(t, errors.Is(err, ErrVarNotFound))
+	assert.Equal(t, "host db1: nosuchvar: variable is not defined", err.Error())
+
+	fallback, err := GetVarOr(host, "nosuchvar", 3)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, fallback)
+	_, err = GetVarOr(host, "replicas", 3)
+	assert.NotNil(t, err)
+}
+
+func TestDecodeVars(t *testing.T) {
+	v := parseString(t, `
+	web1 ansible_port=2222 backup_paths='["/srv"]' replicas=many weight=0.5
+	`)
+	host := v.Hosts["web1"]
+
+	type Settings struct {
+		Port     int           `aini:"ansible_port,default=22"`
+		User     string        `aini:"ansible_user,default=root"`
+		Interval time.Duration `aini:"backup_interval,default=24h"`
+		Paths    []string      `aini:"backup_paths,required"`
+		Weight   *float64      `aini:"weight"`
+		Missing  string        `aini:"missing"`
+		Options  string        `aini:"options,default=a,b"`
+		Ignored  string
+		Skipped  string `aini:"-"`
+	}
+	settings := Settings{Missing: "kept", Ignored: "kept"}
+	assert.Nil(t, host.DecodeVars(&settings))
+	weight := 0.5
+	assert.Equal(t, Settings{
+		Port:     2222,
+		User:     "root",
+		Interval: 24 * time.Hour,
+		Paths:    []string{"/srv"},
+		Weight:   &weight,
+		Missing:  "kept",
+		Options:  "a,b",
+		Ignored:  "kept",
+	}, settings)
+
+	var invalid struct {
+		Replicas int    `aini:"replicas"`
+		Owner    string `aini:"owner,required"`
+		Retries  int    `aini:"retries,default=often"`
+	}
+	err := host.DecodeVars(&invalid)
+	assert.Equal(t, "host web1: replicas (from inventory line of host web1): value 'many' is not an int of 64 bits\n"+
+		"host web1: owner: variable is not defined\n"+
+		"host web1: retries: invalid default: value 'often' is not an int of 64 bits", err.Error())
+	assert.True(t, errors.Is(err, ErrVarNotFound))
+
+	var badTag struct {
+		Port int `aini:"ansible_port,optional"`
+	}
+	assert.NotNil(t, host.DecodeVars(&badTag))
+	assert.NotNil(t, host.DecodeVars(settings))
+	assert.NotNil(t, host.DecodeVars(nil))
+}
@@ -1,11 +1,12 @@
 module github.com/relex/aini
 
-go 1.20
+go 1.23
 
 require (
 	github.com/google/shlex v0.0.0-20191202100458-e7afc7fbc510
 	github.com/samber/lo v1.38.1
 	github.com/stretchr/testify v1.7.0
+	golang.org/x/crypto v0.33.0
 	golang.org/x/exp v0.0.0-20230713183714-613f0c0eb8a1
 	gopkg.in/yaml.v3 v3.0.1
 )
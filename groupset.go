@@ -0,0 +1,156 @@
+package aini
+
+import (
+	"iter"
+	"slices"
+)
+
+// GroupSet is an immutable set of groups, which can be combined with other sets and listed in a SortOrder
+type GroupSet struct {
+	groups map[string]*Group
+	// sequence numbers of groups and hosts in the inventory for InventoryOrder, may be nil
+	sequence     map[*Group]int
+	hostSequence map[*Host]int
+}
+
+// NewGroupSet returns a set of the given groups, ordered by names for InventoryOrder
+func NewGroupSet(groups ...*Group) *GroupSet {
+	set := &GroupSet{groups: make(map[string]*Group, len(groups))}
+	for _, group := range groups {
+		set.groups[group.Name] = group
+	}
+	return set
+}
+
+// GroupSet returns a set of the given groups of the inventory, e.g. Groups of a host
+func (inventory *InventoryData) GroupSet(groups map[string]*Group) *GroupSet {
+	set := inventory.newGroupSet()
+	for name, group := range groups {
+		set.groups[name] = group
+	}
+	return set
+}
+
+// AllGroups returns the set of all groups of the inventory
+func (inventory *InventoryData) AllGroups() *GroupSet {
+	return inventory.GroupSet(inventory.Groups)
+}
+
+func (inventory *InventoryData) newGroupSet() *GroupSet {
+	return &GroupSet{groups: make(map[string]*Group), sequence: inventory.groupSequence, hostSequence: inventory.hostSequence}
+}
+
+// combine returns a new set of groups in the set for which keep returns true, given whether they are in the other
+func (set *GroupSet) combine(other *GroupSet, keep func(inSet bool, inOther bool) bool) *GroupSet {
+	result := &GroupSet{groups: make(map[string]*Group), sequence: set.sequence, hostSequence: set.hostSequence}
+	if result.sequence == nil {
+		result.sequence, result.hostSequence = other.sequence, other.hostSequence
+	}
+	for name, group := range set.groups {
+		if keep(true, other.Contains(name)) {
+			result.groups[name] = group
+		}
+	}
+	for name, group := range other.groups {
+		if keep(set.Contains(name), true) {
+			result.groups[name] = group
+		}
+	}
+	return result
+}
+
+// Len returns the number of groups in the set
+func (set *GroupSet) Len() int {
+	return len(set.groups)
+}
+
+// Contains checks whether the group of the given name is in the set
+func (set *GroupSet) Contains(name string) bool {
+	_, ok := set.groups[name]
+	return ok
+}
+
+// Union returns groups in either of the sets
+func (set *GroupSet) Union(other *GroupSet) *GroupSet {
+	return set.combine(other, func(inSet bool, inOther bool) bool { return inSet || inOther })
+}
+
+// Intersect returns groups in both of the sets
+func (set *GroupSet) Intersect(other *GroupSet) *GroupSet {
+	return set.combine(other, func(inSet bool, inOther bool) bool { return inSet && inOther })
+}
+
+// Difference returns groups in the set but not in the other
+func (set *GroupSet) Difference(other *GroupSet) *GroupSet {
+	return set.combine(other, func(inSet bool, inOther bool) bool { return inSet && !inOther })
+}
+
+// Filter returns groups in the set for which the predicate returns true
+func (set *GroupSet) Filter(predicate func(group *Group) bool) *GroupSet {
+	result := &GroupSet{groups: make(map[string]*Group), sequence: set.sequence, hostSequence: set.hostSequence}
+	for name, group := range set.groups {
+		if predicate(group) {
+			result.groups[name] = group
+		}
+	}
+	return result
+}
+
+// Hosts returns all hosts of groups in the set, including hosts of their descendants
+func (set *GroupSet) Hosts() *HostSet {
+	result := &HostSet{hosts: make(map[string]*Host), sequence: set.hostSequence}
+	for _, group := range set.groups {
+		for name, host := range group.Hosts {
+			result.hosts[name] = host
+		}
+	}
+	return result
+}
+
+// Sorted returns groups in the set in the given order
+func (set *GroupSet) Sorted(order SortOrder) []*Group {
+	groups := make([]*Group, 0, len(set.groups))
+	for _, group := range set.groups {
+		groups = append(groups, group)
+	}
+	if order == InventoryOrder {
+		slices.SortFunc(groups, func(a, b *Group) int { return compareSequence(set.sequence, a, b, a.Name, b.Name) })
+	} else {
+		slices.SortFunc(groups, func(a, b *Group) int { return order.compareNames(a.Name, b.Name) })
+	}
+	return groups
+}
+
+// All yields groups in the set in the given order
+func (set *GroupSet) All(order SortOrder) iter.Seq[*Group] {
+	return slices.Values(set.Sorted(order))
+}
+
+// Groups returns groups in the set ordered by name
+func (set *GroupSet) Groups() []*Group {
+	return set.Sorted(LexicalOrder)
+}
+
+// Names returns names of groups in the set in lexical order
+func (set *GroupSet) Names() []string {
+	return set.SortedNames(LexicalOrder)
+}
+
+// SortedNames returns names of groups in the set in the given order
+func (set *GroupSet) SortedNames(order SortOrder) []string {
+	groups := set.Sorted(order)
+	names := make([]string, len(groups))
+	for i, group := range groups {
+		names[i] = group.Name
+	}
+	return names
+}
+
+// Map returns groups in the set by names, as returned by MatchGroups
+func (set *GroupSet) Map() map[string]*Group {
+	groups := make(map[string]*Group, len(set.groups))
+	for name, group := range set.groups {
+		groups[name] = group
+	}
+	return groups
+}
@@ -0,0 +1,55 @@
+package aini
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupSet(t *testing.T) {
+	v := parseString(t, `
+	[web10]
+	host1
+	[web2]
+	host2
+	[db]
+	host3
+	[db:children]
+	web2
+	`)
+	web, err := v.MatchGroupSet("web*")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"web10", "web2"}, web.Names())
+	db := v.GroupSet(map[string]*Group{"db": v.Groups["db"]})
+
+	assert.Equal(t, []string{"db", "web10", "web2"}, web.Union(db).Names())
+	assert.Equal(t, 0, web.Intersect(db).Len())
+	assert.Equal(t, []string{"web10"}, web.Difference(NewGroupSet(v.Groups["web2"])).Names())
+	assert.True(t, web.Contains("web2"))
+	assert.False(t, web.Contains("db"))
+
+	assert.Equal(t, []string{"host2", "host3"}, db.Hosts().Names())
+	assert.Equal(t, []string{"host1", "host2", "host3"}, web.Union(db).Hosts().Names())
+
+	assert.Equal(t, []string{"web2", "web10"}, getGroupNamesOf(web.Sorted(NaturalOrder)))
+	assert.Equal(t, []string{"web2", "web10"}, web.SortedNames(NaturalOrder))
+	assert.Equal(t, []string{"web10", "web2", "db"}, getGroupNamesOf(web.Union(db).Sorted(InventoryOrder)))
+
+	iterated := []string{}
+	for group := range v.AllGroups().Filter(func(group *Group) bool { return len(group.Hosts) > 1 }).All(LexicalOrder) {
+		iterated = append(iterated, group.Name)
+	}
+	assert.Equal(t, []string{"all", "db"}, iterated)
+	assert.Equal(t, v.Groups, v.AllGroups().Map())
+
+	_, err = v.MatchGroupSet("[")
+	assert.NotNil(t, err)
+}
+
+func getGroupNamesOf(groups []*Group) []string {
+	names := make([]string, 0, len(groups))
+	for _, group := range groups {
+		names = append(names, group.Name)
+	}
+	return names
+}
@@ -0,0 +1,100 @@
+package aini
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ParseHostList parses comma-separated list of hosts as Ansible's `host_list` and `advanced_host_list` inventory plugins.
+// All hosts are put into the ungrouped group.
+//
+// e.g. "web01,web02:2222,db[01:03],[fd00::1]:2222,fd00::2,"
+func ParseHostList(list string) (*InventoryData, error) {
+	inventory := &InventoryData{}
+	inventory.init()
+	if err := inventory.parseHostList(context.Background(), list); err != nil {
+		return inventory, err
+	}
+	inventory.Reconcile()
+	return inventory, nil
+}
+
+// IsHostList checks whether the given inventory string is a host list instead of a path to an existing file
+func IsHostList(inventoryPath string) bool {
+	if !strings.Contains(inventoryPath, ",") {
+		return false
+	}
+	_, err := os.Stat(inventoryPath)
+	return err != nil
+}
+
+func (inventory *InventoryData) parseHostList(ctx context.Context, list string) error {
+	ungrouped := inventory.getOrCreateGroup("ungrouped")
+	for _, item := range strings.Split(list, ",") {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		hostpattern, port, portSet, err := parseHostListAddress(item)
+		if err != nil {
+			return fmt.Errorf("invalid host list entry '%s': %w", item, err)
+		}
+		hostnames := []string{hostpattern}
+		if net.ParseIP(hostpattern) == nil {
+			if hostnames, err = expandHostPatternContext(ctx, hostpattern, inventory.parseOptions.expansionLimit()); err != nil {
+				return fmt.Errorf("invalid host list entry '%s': %w", item, err)
+			}
+		}
+		for _, hostname := range hostnames {
+			host := inventory.getOrCreateHost(hostname)
+			if portSet {
+				host.Port = port
+				host.portSet = true
+			}
+			host.DirectGroups[ungrouped.Name] = ungrouped
+		}
+		if err := inventory.checkHostLimit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseHostListAddress splits host list entry into host pattern and port, supporting IPv6 forms `[addr]:port` and `addr`
+func parseHostListAddress(item string) (string, int, bool, error) {
+	if strings.HasPrefix(item, "[") {
+		if end := strings.Index(item, "]"); end > 0 && net.ParseIP(item[1:end]) != nil {
+			address, rest := item[1:end], item[end+1:]
+			if rest == "" {
+				return address, defaultPort, false, nil
+			}
+			if !strings.HasPrefix(rest, ":") {
+				return "", 0, false, fmt.Errorf("unexpected '%s' after address", rest)
+			}
+			port, err := strconv.Atoi(rest[1:])
+			return address, port, true, err
+		}
+	}
+	if strings.Count(item, ":") > 1 && net.ParseIP(item) != nil {
+		return item, defaultPort, false, nil
+	}
+	return getHostPort(item)
+}
+
+// hostListSource parses comma-separated host lists given in place of inventory file path
+type hostListSource struct{}
+
+func (hostListSource) CanParse(path string) bool {
+	return IsHostList(path)
+}
+
+func (hostListSource) Parse(ctx context.Context, path string, inventory *InventoryData) error {
+	return inventory.parseHostList(ctx, path)
+}
@@ -0,0 +1,33 @@
+package aini
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseHostList(t *testing.T) {
+	v, err := ParseHostList("web01, web02:2222,db[01:03],[fd00::1]:2223,fd00::2,[fd00::3],10.0.0.1:2224,")
+	assert.Nil(t, err)
+
+	assert.Len(t, v.Hosts, 9)
+	assert.Len(t, v.Groups, 2, "Only all and ungrouped are expected")
+	for _, name := range []string{"web01", "web02", "db01", "db02", "db03", "fd00::1", "fd00::2", "fd00::3", "10.0.0.1"} {
+		assert.Contains(t, v.Groups["ungrouped"].Hosts, name)
+	}
+	assert.Equal(t, 22, v.Hosts["web01"].Port)
+	assert.Equal(t, 2222, v.Hosts["web02"].Port)
+	assert.Equal(t, 2223, v.Hosts["fd00::1"].Port)
+	assert.Equal(t, 22, v.Hosts["fd00::2"].Port)
+	assert.Equal(t, 22, v.Hosts["fd00::3"].Port)
+	assert.Equal(t, 2224, v.Hosts["10.0.0.1"].Port)
+
+	_, err = ParseHostList("web01,web02:abc")
+	assert.NotNil(t, err)
+
+	_, err = ParseHostList("web[01:]")
+	assert.NotNil(t, err)
+
+	assert.True(t, IsHostList("web01,"))
+	assert.False(t, IsHostList("web01"))
+}
@@ -0,0 +1,268 @@
+package aini
+
+import (
+	"iter"
+	"math/bits"
+	"slices"
+)
+
+// bitset is a set of small non-negative integers
+type bitset []uint64
+
+func newBitset(size int) bitset {
+	return make(bitset, (size+63)/64)
+}
+
+func (set bitset) add(n int) {
+	set[n/64] |= 1 << (uint(n) % 64)
+}
+
+func (set bitset) has(n int) bool {
+	return n >= 0 && n/64 < len(set) && set[n/64]&(1<<(uint(n)%64)) != 0
+}
+
+func (set bitset) count() int {
+	total := 0
+	for _, word := range set {
+		total += bits.OnesCount64(word)
+	}
+	return total
+}
+
+// unionWith adds all members of other to the set
+func (set bitset) unionWith(other bitset) {
+	for i := range set {
+		set[i] |= other[i]
+	}
+}
+
+// intersectWith removes members not in other from the set
+func (set bitset) intersectWith(other bitset) {
+	for i := range set {
+		set[i] &= other[i]
+	}
+}
+
+// differenceWith removes members of other from the set
+func (set bitset) differenceWith(other bitset) {
+	for i := range set {
+		set[i] &^= other[i]
+	}
+}
+
+// each calls fn with all members in increasing order
+func (set bitset) each(fn func(n int)) {
+	for i, word := range set {
+		for word != 0 {
+			fn(i*64 + bits.TrailingZeros64(word))
+			word &= word - 1
+		}
+	}
+}
+
+// HostSet is an immutable set of hosts, which can be combined with other sets and listed in a SortOrder.
+//
+// Sets of an InventoryIndex keep hosts as bitsets, so that operations between sets of the same index are fast.
+type HostSet struct {
+	// hosts by names, nil for sets of InventoryIndex
+	hosts map[string]*Host
+	// index and bits are set for sets of InventoryIndex
+	index *InventoryIndex
+	bits  bitset
+	// sequence numbers of hosts in the inventory for InventoryOrder, may be nil
+	sequence map[*Host]int
+}
+
+// NewHostSet returns a set of the given hosts, ordered by names for InventoryOrder
+func NewHostSet(hosts ...*Host) *HostSet {
+	set := &HostSet{hosts: make(map[string]*Host, len(hosts))}
+	for _, host := range hosts {
+		set.hosts[host.Name] = host
+	}
+	return set
+}
+
+// HostSet returns a set of the given hosts of the inventory, e.g. Hosts of a group
+func (inventory *InventoryData) HostSet(hosts map[string]*Host) *HostSet {
+	set := &HostSet{hosts: make(map[string]*Host, len(hosts)), sequence: inventory.hostSequence}
+	for name, host := range hosts {
+		set.hosts[name] = host
+	}
+	return set
+}
+
+// AllHosts returns the set of all hosts of the inventory
+func (inventory *InventoryData) AllHosts() *HostSet {
+	return inventory.HostSet(inventory.Hosts)
+}
+
+func (index *InventoryIndex) newHostSet() *HostSet {
+	return &HostSet{index: index, bits: newBitset(len(index.hosts)), sequence: index.hostSequence}
+}
+
+// sameIndex checks whether both sets are of the same InventoryIndex, whose host IDs are comparable
+func (set *HostSet) sameIndex(other *HostSet) bool {
+	return set.index != nil && set.index == other.index
+}
+
+// combine returns a new set of hosts in the set for which keep returns true, given whether they are in the other
+func (set *HostSet) combine(other *HostSet, keep func(inSet bool, inOther bool) bool) *HostSet {
+	result := &HostSet{hosts: make(map[string]*Host), sequence: set.sequence}
+	if result.sequence == nil {
+		result.sequence = other.sequence
+	}
+	for host := range set.all() {
+		if keep(true, other.Contains(host.Name)) {
+			result.hosts[host.Name] = host
+		}
+	}
+	for host := range other.all() {
+		if keep(set.Contains(host.Name), true) {
+			result.hosts[host.Name] = host
+		}
+	}
+	return result
+}
+
+// combineBits returns a new set of the same index with bits of the set modified by op
+func (set *HostSet) combineBits(other *HostSet, op func(bitset, bitset)) *HostSet {
+	result := &HostSet{index: set.index, bits: append(bitset(nil), set.bits...), sequence: set.sequence}
+	op(result.bits, other.bits)
+	return result
+}
+
+// Len returns the number of hosts in the set
+func (set *HostSet) Len() int {
+	if set.index != nil {
+		return set.bits.count()
+	}
+	return len(set.hosts)
+}
+
+// Contains checks whether the host of the given name is in the set
+func (set *HostSet) Contains(name string) bool {
+	if set.index != nil {
+		id, ok := set.index.hostIDs[name]
+		return ok && set.bits.has(id)
+	}
+	_, ok := set.hosts[name]
+	return ok
+}
+
+// Union returns hosts in either of the sets
+func (set *HostSet) Union(other *HostSet) *HostSet {
+	if set.sameIndex(other) {
+		return set.combineBits(other, bitset.unionWith)
+	}
+	return set.combine(other, func(inSet bool, inOther bool) bool { return inSet || inOther })
+}
+
+// Intersect returns hosts in both of the sets
+func (set *HostSet) Intersect(other *HostSet) *HostSet {
+	if set.sameIndex(other) {
+		return set.combineBits(other, bitset.intersectWith)
+	}
+	return set.combine(other, func(inSet bool, inOther bool) bool { return inSet && inOther })
+}
+
+// Difference returns hosts in the set but not in the other
+func (set *HostSet) Difference(other *HostSet) *HostSet {
+	if set.sameIndex(other) {
+		return set.combineBits(other, bitset.differenceWith)
+	}
+	return set.combine(other, func(inSet bool, inOther bool) bool { return inSet && !inOther })
+}
+
+// Filter returns hosts in the set for which the predicate returns true
+func (set *HostSet) Filter(predicate func(host *Host) bool) *HostSet {
+	if set.index != nil {
+		result := set.index.newHostSet()
+		set.bits.each(func(id int) {
+			if predicate(set.index.hosts[id]) {
+				result.bits.add(id)
+			}
+		})
+		return result
+	}
+	result := &HostSet{hosts: make(map[string]*Host), sequence: set.sequence}
+	for name, host := range set.hosts {
+		if predicate(host) {
+			result.hosts[name] = host
+		}
+	}
+	return result
+}
+
+// all yields hosts in the set in no particular order
+func (set *HostSet) all() iter.Seq[*Host] {
+	return func(yield func(*Host) bool) {
+		if set.index == nil {
+			for _, host := range set.hosts {
+				if !yield(host) {
+					return
+				}
+			}
+			return
+		}
+		for i, word := range set.bits {
+			for word != 0 {
+				if !yield(set.index.hosts[i*64+bits.TrailingZeros64(word)]) {
+					return
+				}
+				word &= word - 1
+			}
+		}
+	}
+}
+
+// Sorted returns hosts in the set in the given order
+func (set *HostSet) Sorted(order SortOrder) []*Host {
+	hosts := make([]*Host, 0, set.Len())
+	for host := range set.all() {
+		hosts = append(hosts, host)
+	}
+	// hosts of an index are already ordered by names
+	if set.index != nil && order == LexicalOrder {
+		return hosts
+	}
+	if order == InventoryOrder {
+		slices.SortFunc(hosts, func(a, b *Host) int { return compareSequence(set.sequence, a, b, a.Name, b.Name) })
+	} else {
+		slices.SortFunc(hosts, func(a, b *Host) int { return order.compareNames(a.Name, b.Name) })
+	}
+	return hosts
+}
+
+// All yields hosts in the set in the given order
+func (set *HostSet) All(order SortOrder) iter.Seq[*Host] {
+	return slices.Values(set.Sorted(order))
+}
+
+// Hosts returns hosts in the set ordered by name
+func (set *HostSet) Hosts() []*Host {
+	return set.Sorted(LexicalOrder)
+}
+
+// Names returns names of hosts in the set in lexical order
+func (set *HostSet) Names() []string {
+	return set.SortedNames(LexicalOrder)
+}
+
+// SortedNames returns names of hosts in the set in the given order
+func (set *HostSet) SortedNames(order SortOrder) []string {
+	hosts := set.Sorted(order)
+	names := make([]string, len(hosts))
+	for i, host := range hosts {
+		names[i] = host.Name
+	}
+	return names
+}
+
+// Map returns hosts in the set by names, as returned by MatchHostsByPatterns
+func (set *HostSet) Map() map[string]*Host {
+	hosts := make(map[string]*Host, set.Len())
+	for host := range set.all() {
+		hosts[host.Name] = host
+	}
+	return hosts
+}
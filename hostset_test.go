@@ -0,0 +1,121 @@
+package aini
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHostSet(t *testing.T) {
+	v := parseString(t, `
+	[web]
+	host1
+	host2
+	host3
+	[db]
+	host3
+	host4
+	`)
+	index := v.BuildIndex()
+	web, db := index.Group("web"), index.Group("db")
+
+	assert.Equal(t, []string{"host1", "host2", "host3", "host4"}, web.Union(db).Names())
+	assert.Equal(t, []string{"host3"}, web.Intersect(db).Names())
+	assert.Equal(t, []string{"host1", "host2"}, web.Difference(db).Names())
+	// operands are not modified
+	assert.Equal(t, 3, web.Len())
+	assert.Equal(t, 2, db.Len())
+
+	assert.True(t, web.Contains("host1"))
+	assert.False(t, web.Contains("host4"))
+	assert.False(t, web.Contains("nosuch"))
+	assert.Equal(t, []*Host{v.Hosts["host3"], v.Hosts["host4"]}, db.Hosts())
+
+	// sets of different indexes or without index are combined by names
+	other := parseString(t, "host1\nhost9\n").BuildIndex()
+	assert.Equal(t, []string{"host1", "host2", "host3", "host9"}, web.Union(other.All()).Names())
+	assert.Equal(t, []string{"host1"}, web.Intersect(other.All()).Names())
+	assert.Equal(t, []string{"host2", "host3"}, web.Difference(NewHostSet(v.Hosts["host1"])).Names())
+}
+
+func TestHostSetOrders(t *testing.T) {
+	v := parseString(t, `
+	web10
+	web2
+	[db]
+	db1
+	web1
+	`)
+	for _, set := range []*HostSet{v.AllHosts(), v.BuildIndex().All()} {
+		assert.Equal(t, []string{"db1", "web1", "web10", "web2"}, getHostNames(set.Sorted(LexicalOrder)))
+		assert.Equal(t, []string{"db1", "web1", "web2", "web10"}, getHostNames(set.Sorted(NaturalOrder)))
+		assert.Equal(t, []string{"web10", "web2", "db1", "web1"}, getHostNames(set.Sorted(InventoryOrder)))
+		assert.Equal(t, []string{"db1", "web1", "web2", "web10"}, set.SortedNames(NaturalOrder))
+
+		iterated := []string{}
+		for host := range set.All(NaturalOrder) {
+			iterated = append(iterated, host.Name)
+			if len(iterated) == 2 {
+				break
+			}
+		}
+		assert.Equal(t, []string{"db1", "web1"}, iterated)
+	}
+
+	// hosts without known positions are ordered by names after the others
+	unknown := &Host{Name: "a"}
+	assert.Equal(t, []string{"web2", "db1", "a"}, getHostNames(v.HostSet(v.Hosts).Filter(func(host *Host) bool {
+		return host.Name == "web2" || host.Name == "db1"
+	}).Union(NewHostSet(unknown)).Sorted(InventoryOrder)))
+}
+
+func TestHostSetFilter(t *testing.T) {
+	v := parseString(t, `
+	host1 env=prod
+	host2 env=dev
+	host3 env=prod
+	`)
+	prod := func(host *Host) bool { return host.Vars["env"] == "prod" }
+	assert.Equal(t, []string{"host1", "host3"}, v.AllHosts().Filter(prod).Names())
+	assert.Equal(t, []string{"host1", "host3"}, v.BuildIndex().All().Filter(prod).Names())
+	assert.Equal(t, 0, NewHostSet().Filter(prod).Len())
+}
+
+func TestMatchHostSet(t *testing.T) {
+	v := parseString(t, `
+	[web]
+	web1
+	web2
+	[db]
+	db1
+	`)
+	web, err := v.MatchHostSetByPatterns("web:db:!db1")
+	assert.Nil(t, err)
+	assert.Equal(t, v.Hosts["web1"], web.Hosts()[0])
+	assert.Equal(t, map[string]*Host{"web1": v.Hosts["web1"], "web2": v.Hosts["web2"]}, web.Map())
+
+	_, err = v.MatchHostSetByPatterns("!web")
+	assert.NotNil(t, err)
+
+	db, err := v.MatchHostSet("db*")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"db1"}, db.Names())
+
+	_, err = v.MatchHostSet("[")
+	assert.NotNil(t, err)
+}
+
+func TestBitset(t *testing.T) {
+	set := newBitset(130)
+	for _, n := range []int{0, 63, 64, 129} {
+		set.add(n)
+	}
+	assert.Equal(t, 4, set.count())
+	assert.True(t, set.has(129))
+	assert.False(t, set.has(1))
+	assert.False(t, set.has(-1))
+	assert.False(t, set.has(1000))
+	members := []int{}
+	set.each(func(n int) { members = append(members, n) })
+	assert.Equal(t, []int{0, 63, 64, 129}, members)
+}
@@ -0,0 +1,196 @@
+package aini
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// InventoryIndex allows fast matching of host patterns on large inventories.
+// Hosts are numbered in the order of names and groups keep their hosts as bitsets,
+// so that patterns of group names are resolved by set operations instead of globbing each host.
+//
+// The index is a snapshot: it must be rebuilt after hosts or groups of the inventory change.
+type InventoryIndex struct {
+	hosts   []*Host
+	hostIDs map[string]int
+	// hostKeys and groupKeys are sorted by key, which is the name or lowercased name in case-insensitive mode
+	hostKeys        []indexedHost
+	groupKeys       []indexedGroup
+	caseInsensitive bool
+	hostSequence    map[*Host]int
+}
+
+type indexedHost struct {
+	key string
+	id  int
+}
+
+type indexedGroup struct {
+	key   string
+	hosts bitset
+}
+
+// BuildIndex indexes hosts and groups of the inventory, which must be reconciled
+func (inventory *InventoryData) BuildIndex() *InventoryIndex {
+	index := &InventoryIndex{
+		hosts:           HostMapListValues(inventory.Hosts),
+		hostIDs:         make(map[string]int, len(inventory.Hosts)),
+		hostKeys:        make([]indexedHost, 0, len(inventory.Hosts)),
+		groupKeys:       make([]indexedGroup, 0, len(inventory.Groups)),
+		caseInsensitive: inventory.caseInsensitive,
+		hostSequence:    inventory.hostSequence,
+	}
+	for id, host := range index.hosts {
+		index.hostIDs[host.Name] = id
+		index.hostKeys = append(index.hostKeys, indexedHost{key: index.key(host.Name), id: id})
+	}
+	for _, group := range inventory.Groups {
+		members := newBitset(len(index.hosts))
+		for name := range group.Hosts {
+			if id, ok := index.hostIDs[name]; ok {
+				members.add(id)
+			}
+		}
+		index.groupKeys = append(index.groupKeys, indexedGroup{key: index.key(group.Name), hosts: members})
+	}
+	sort.Slice(index.hostKeys, func(i, j int) bool { return index.hostKeys[i].key < index.hostKeys[j].key })
+	sort.Slice(index.groupKeys, func(i, j int) bool { return index.groupKeys[i].key < index.groupKeys[j].key })
+	return index
+}
+
+func (index *InventoryIndex) key(name string) string {
+	if index.caseInsensitive {
+		return strings.ToLower(name)
+	}
+	return name
+}
+
+// All returns the set of all hosts
+func (index *InventoryIndex) All() *HostSet {
+	set := index.newHostSet()
+	for id := range index.hosts {
+		set.bits.add(id)
+	}
+	return set
+}
+
+// Empty returns an empty set of hosts
+func (index *InventoryIndex) Empty() *HostSet {
+	return index.newHostSet()
+}
+
+// Host returns the set of the host of the given name, empty if it doesn't exist.
+// Names are compared case-insensitively if the inventory was set by SetCaseInsensitive when indexed.
+func (index *InventoryIndex) Host(name string) *HostSet {
+	set := index.newHostSet()
+	if id, ok := index.hostIDs[name]; ok {
+		set.bits.add(id)
+		return set
+	}
+	key := index.key(name)
+	for _, host := range index.hostRange(key) {
+		if host.key == key {
+			set.bits.add(host.id)
+		}
+	}
+	return set
+}
+
+// Group returns the set of all hosts of the group including its descendants, empty if it doesn't exist
+func (index *InventoryIndex) Group(name string) *HostSet {
+	set := index.newHostSet()
+	key := index.key(name)
+	for _, group := range index.groupRange(key) {
+		if group.key == key {
+			set.bits.unionWith(group.hosts)
+		}
+	}
+	return set
+}
+
+// MatchHostsByPatterns returns hosts matching Ansible host patterns, the same as InventoryData.MatchHostsByPatterns
+func (index *InventoryIndex) MatchHostsByPatterns(patterns string) (*HostSet, error) {
+	pattern, err := CompilePattern(patterns)
+	if err != nil {
+		return nil, err
+	}
+	return pattern.MatchHostSet(index), nil
+}
+
+// MatchHostSet returns hosts of the index matching the patterns, using set operations on groups
+func (pattern *Pattern) MatchHostSet(index *InventoryIndex) *HostSet {
+	result := index.newHostSet()
+	if pattern.matchesNothing {
+		return result
+	}
+	var intersections, exclusions []*HostSet
+	for termIndex := range pattern.terms {
+		term := &pattern.terms[termIndex]
+		switch term.kind {
+		case includeTerm:
+			result.bits.unionWith(index.matchTerm(term).bits)
+		case intersectTerm:
+			intersections = append(intersections, index.matchTerm(term))
+		case excludeTerm:
+			exclusions = append(exclusions, index.matchTerm(term))
+		}
+	}
+	for _, set := range intersections {
+		result.bits.intersectWith(set.bits)
+	}
+	for _, set := range exclusions {
+		result.bits.differenceWith(set.bits)
+	}
+	return result
+}
+
+// matchTerm returns hosts whose names or names of groups match the sub-pattern
+func (index *InventoryIndex) matchTerm(term *patternTerm) *HostSet {
+	if term.kind == includeTerm && term.glob == "" {
+		return index.All()
+	}
+	glob := term.glob
+	if index.caseInsensitive {
+		glob = term.lowerGlob
+	}
+	set := index.newHostSet()
+	for _, group := range index.groupRange(globPrefix(glob)) {
+		// syntax is checked by CompilePattern
+		if matched, _ := path.Match(glob, group.key); matched {
+			set.bits.unionWith(group.hosts)
+		}
+	}
+	for _, host := range index.hostRange(globPrefix(glob)) {
+		if matched, _ := path.Match(glob, host.key); matched {
+			set.bits.add(host.id)
+		}
+	}
+	return set
+}
+
+// globPrefix returns the literal part of the glob before any special characters
+func globPrefix(glob string) string {
+	if end := strings.IndexAny(glob, `*?[\`); end >= 0 {
+		return glob[:end]
+	}
+	return glob
+}
+
+// groupRange returns indexed groups whose keys start with the prefix
+func (index *InventoryIndex) groupRange(prefix string) []indexedGroup {
+	start := sort.Search(len(index.groupKeys), func(i int) bool { return index.groupKeys[i].key >= prefix })
+	end := start + sort.Search(len(index.groupKeys)-start, func(i int) bool {
+		return !strings.HasPrefix(index.groupKeys[start+i].key, prefix)
+	})
+	return index.groupKeys[start:end]
+}
+
+// hostRange returns indexed hosts whose keys start with the prefix
+func (index *InventoryIndex) hostRange(prefix string) []indexedHost {
+	start := sort.Search(len(index.hostKeys), func(i int) bool { return index.hostKeys[i].key >= prefix })
+	end := start + sort.Search(len(index.hostKeys)-start, func(i int) bool {
+		return !strings.HasPrefix(index.hostKeys[start+i].key, prefix)
+	})
+	return index.hostKeys[start:end]
+}
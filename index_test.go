@@ -0,0 +1,126 @@
+package aini
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/exp/maps"
+)
+
+func TestIndexMatchesLikeMatchHostsByPatterns(t *testing.T) {
+	v, err := ParseFile("test_data/inventory")
+	assert.Nil(t, err)
+	index := v.BuildIndex()
+
+	for _, patterns := range []string{
+		"all", "*", "web", "web:!nginx", "web:&nginx", "host*", "host[1-3]:apache", "Tom*", "tom*",
+		"all:!host1:!apache", "nginx:&web:&host1", ":web", "nosuch", "*:&*Cat", "h?st5", "web:!all",
+	} {
+		expected, err := v.MatchHostsByPatterns(patterns)
+		assert.Nil(t, err, patterns)
+		actual, err := index.MatchHostsByPatterns(patterns)
+		assert.Nil(t, err, patterns)
+		expectedNames := maps.Keys(expected)
+		sort.Strings(expectedNames)
+		assert.Equal(t, expectedNames, actual.Names(), patterns)
+		assert.Equal(t, expected, actual.Map(), patterns)
+	}
+
+	_, err = index.MatchHostsByPatterns("!web")
+	assert.NotNil(t, err)
+}
+
+func TestIndexCaseInsensitive(t *testing.T) {
+	v, err := ParseFile("test_data/inventory")
+	assert.Nil(t, err)
+	assert.Nil(t, v.SetCaseInsensitive())
+	index := v.BuildIndex()
+
+	hosts, err := index.MatchHostsByPatterns("TOMCAT:HOST1")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Host7", "host1"}, hosts.Names())
+	assert.Equal(t, []string{"Host7"}, index.Host("host7").Names())
+	assert.Equal(t, []string{"Host7"}, index.Group("tomcat").Names())
+}
+
+func TestIndexSets(t *testing.T) {
+	v, err := ParseFile("test_data/inventory")
+	assert.Nil(t, err)
+	index := v.BuildIndex()
+
+	assert.Equal(t, 7, index.All().Len())
+	assert.Equal(t, 0, index.Empty().Len())
+	assert.Equal(t, []string{"host1", "host2", "host3", "host4", "host5", "host6"}, index.Group("web").Names())
+	assert.Equal(t, 0, index.Group("nosuch").Len())
+	assert.Equal(t, []string{"host5"}, index.Host("host5").Names())
+	assert.Equal(t, 0, index.Host("nosuch").Len())
+}
+
+// generateInventory makes an inventory of the given numbers of hosts and groups,
+// with every host in three groups and groups nested under ten parent groups
+func generateInventory(hosts int, groups int) string {
+	var sb strings.Builder
+	for g := 0; g < groups; g++ {
+		fmt.Fprintf(&sb, "[group%03d]\n", g)
+		for h := g; h < hosts; h += groups {
+			fmt.Fprintf(&sb, "host%05d.example.com\n", h)
+		}
+		for h := (g * 7) % groups; h < hosts; h += groups {
+			fmt.Fprintf(&sb, "host%05d.example.com\n", h)
+		}
+		for h := (g * 13) % groups; h < hosts; h += groups {
+			fmt.Fprintf(&sb, "host%05d.example.com\n", h)
+		}
+	}
+	for p := 0; p < 10; p++ {
+		fmt.Fprintf(&sb, "[region%d:children]\n", p)
+		for g := p; g < groups; g += 10 {
+			fmt.Fprintf(&sb, "group%03d\n", g)
+		}
+	}
+	return sb.String()
+}
+
+var benchmarkPatterns = "region1:region2:group1*:&group*5:!group005:!host0000*"
+
+func BenchmarkMatchHostsByPatterns(b *testing.B) {
+	v, err := ParseString(generateInventory(60000, 300))
+	assert.Nil(b, err)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = v.MatchHostsByPatterns(benchmarkPatterns)
+	}
+}
+
+func BenchmarkIndexMatchHostsByPatterns(b *testing.B) {
+	v, err := ParseString(generateInventory(60000, 300))
+	assert.Nil(b, err)
+	index := v.BuildIndex()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = index.MatchHostsByPatterns(benchmarkPatterns)
+	}
+}
+
+func BenchmarkBuildIndex(b *testing.B) {
+	v, err := ParseString(generateInventory(60000, 300))
+	assert.Nil(b, err)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		v.BuildIndex()
+	}
+}
+
+func TestIndexGeneratedInventory(t *testing.T) {
+	v, err := ParseString(generateInventory(6000, 300))
+	assert.Nil(t, err)
+	expected, err := v.MatchHostsByPatterns(benchmarkPatterns)
+	assert.Nil(t, err)
+	actual, err := v.BuildIndex().MatchHostsByPatterns(benchmarkPatterns)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, expected)
+	assert.Equal(t, expected, actual.Map())
+}
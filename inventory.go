@@ -21,6 +21,7 @@ func (inventory *InventoryData) Reconcile() {
 		group.clearData(make(map[string]struct{}, len(inventory.Groups)))
 	}
 
+	inventory.init()
 	allGroup := inventory.getOrCreateGroup("all")
 	ungroupedGroup := inventory.getOrCreateGroup("ungrouped")
 	ungroupedGroup.DirectParents[allGroup.Name] = allGroup
@@ -37,6 +38,10 @@ func (inventory *InventoryData) Reconcile() {
 
 	// Calculate intergroup relationships
 	for _, group := range inventory.Groups {
+		if group.DirectParents == nil {
+			// groups may be made outside of the package without AddGroup
+			group.DirectParents = make(map[string]*Group)
+		}
 		group.DirectParents[allGroup.Name] = allGroup
 		for _, ancestor := range group.ListParentGroupsOrdered() {
 			group.Parents[ancestor.Name] = ancestor
@@ -85,6 +90,53 @@ func (group *Group) clearData(visited map[string]struct{}) {
 	}
 }
 
+// init makes empty maps of groups, hosts and their locations if they are not set yet
+func (inventory *InventoryData) init() {
+	if inventory.Groups == nil {
+		inventory.Groups = make(map[string]*Group)
+	}
+	if inventory.Hosts == nil {
+		inventory.Hosts = make(map[string]*Host)
+	}
+	if inventory.hostLines == nil {
+		inventory.hostLines = make(map[string][]hostDefinition)
+	}
+	if inventory.groupLines == nil {
+		inventory.groupLines = make(map[string]int)
+	}
+}
+
+// AddHost returns the host of the given name, adding it to the inventory in the "ungrouped" group if it doesn't exist.
+// It's meant for custom inventory sources; call Reconcile after all changes if the inventory is not parsed by a source.
+func (inventory *InventoryData) AddHost(name string) *Host {
+	inventory.init()
+	host, ok := inventory.Hosts[name]
+	if !ok {
+		host = inventory.getOrCreateHost(name)
+		inventory.addHostToGroup(host, inventory.getOrCreateGroup("ungrouped"))
+	}
+	return host
+}
+
+// AddGroup returns the group of the given name, adding it to the inventory if it doesn't exist
+func (inventory *InventoryData) AddGroup(name string) *Group {
+	inventory.init()
+	return inventory.getOrCreateGroup(name)
+}
+
+// AddChildGroup makes the child group a direct child of the parent group, adding both if they don't exist
+func (inventory *InventoryData) AddChildGroup(parentName string, childName string) {
+	parent := inventory.AddGroup(parentName)
+	child := inventory.AddGroup(childName)
+	child.DirectParents[parent.Name] = parent
+}
+
+// AddHostToGroup makes the host a direct member of the group, adding both if they don't exist.
+// The host is removed from "ungrouped" when added to any other group.
+func (inventory *InventoryData) AddHostToGroup(hostName string, groupName string) {
+	inventory.addHostToGroup(inventory.AddHost(hostName), inventory.AddGroup(groupName))
+}
+
 // getOrCreateGroup return group from inventory if exists or creates empty Group with given name
 func (inventory *InventoryData) getOrCreateGroup(groupName string) *Group {
 	if group, ok := inventory.Groups[groupName]; ok {
@@ -102,6 +154,10 @@ func (inventory *InventoryData) getOrCreateGroup(groupName string) *Group {
 		FileVars:      make(map[string]string),
 	}
 	inventory.Groups[groupName] = g
+	if inventory.groupSequence == nil {
+		inventory.groupSequence = make(map[*Group]int)
+	}
+	inventory.groupSequence[g] = len(inventory.groupSequence)
 	return g
 }
 
@@ -112,7 +168,7 @@ func (inventory *InventoryData) getOrCreateHost(hostName string) *Host {
 	}
 	h := &Host{
 		Name:   hostName,
-		Port:   22,
+		Port:   defaultPort,
 		Groups: make(map[string]*Group),
 		Vars:   make(map[string]string),
 
@@ -121,6 +177,10 @@ func (inventory *InventoryData) getOrCreateHost(hostName string) *Host {
 		FileVars:      make(map[string]string),
 	}
 	inventory.Hosts[hostName] = h
+	if inventory.hostSequence == nil {
+		inventory.hostSequence = make(map[*Host]int)
+	}
+	inventory.hostSequence[h] = len(inventory.hostSequence)
 	return h
 }
 
@@ -0,0 +1,284 @@
+package aini
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// LintSeverity is the severity of lint issues
+type LintSeverity string
+
+// Severities of lint issues, from the most severe
+const (
+	LintError   LintSeverity = "error"
+	LintWarning LintSeverity = "warning"
+	LintInfo    LintSeverity = "info"
+)
+
+// LintIssue is a problem found in inventory by Lint
+type LintIssue struct {
+	Rule     string       `json:"rule"`
+	Severity LintSeverity `json:"severity"`
+	Message  string       `json:"message"`
+	// File is the inventory file or vars file having the problem, empty if unknown
+	File string `json:"file,omitempty"`
+	// Line is the line number in File, 0 if unknown
+	Line  int    `json:"line,omitempty"`
+	Host  string `json:"host,omitempty"`
+	Group string `json:"group,omitempty"`
+}
+
+// LintRule checks inventory for a kind of problems
+type LintRule struct {
+	Name        string
+	Description string
+	Severity    LintSeverity
+	// Check returns problems found in the inventory. Rule and Severity of the returned issues are set by Lint.
+	Check func(inventory *InventoryData) []LintIssue
+}
+
+// DefaultLintRules returns all built-in lint rules with their default severities.
+//
+// Locations in inventory files are only known for inventories parsed from INI files,
+// and vars files are only checked in directories loaded by AddVars and the like.
+func DefaultLintRules() []LintRule {
+	return []LintRule{
+		{Name: "unmatched-vars-file", Severity: LintWarning, Description: "group_vars or host_vars file matching no group or host", Check: lintUnmatchedVarsFiles},
+		{Name: "empty-group", Severity: LintWarning, Description: "group without hosts and child groups", Check: lintEmptyGroups},
+		{Name: "ungrouped-host", Severity: LintInfo, Description: "host only in the ungrouped group", Check: lintUngroupedHosts},
+		{Name: "invalid-hostname", Severity: LintError, Description: "host name which is neither an IP address nor a valid DNS name", Check: lintInvalidHostnames},
+		{Name: "conflicting-host-definitions", Severity: LintError, Description: "host defined on multiple lines with different values of inline vars", Check: lintConflictingHostDefinitions},
+		{Name: "overridden-group-var", Severity: LintWarning, Description: "group variable overridden by every host of the group", Check: lintOverriddenGroupVars},
+		{Name: "invalid-group-name", Severity: LintWarning, Description: "group name which Ansible would sanitize", Check: lintInvalidGroupNames},
+		{Name: "case-conflict", Severity: LintError, Description: "host or group names differing only by case", Check: lintCaseConflicts},
+		{Name: "vars-file-error", Severity: LintError, Description: "group_vars or host_vars file which cannot be loaded", Check: lintVarsFileErrors},
+	}
+}
+
+// LintRuleByName returns the built-in lint rule of the given name
+func LintRuleByName(name string) (LintRule, bool) {
+	for _, rule := range DefaultLintRules() {
+		if rule.Name == name {
+			return rule, true
+		}
+	}
+	return LintRule{}, false
+}
+
+// Lint checks the inventory by the given rules, returning issues ordered by file, line, rule and message
+func Lint(inventory *InventoryData, rules []LintRule) []LintIssue {
+	issues := make([]LintIssue, 0)
+	for _, rule := range rules {
+		for _, issue := range rule.Check(inventory) {
+			issue.Rule = rule.Name
+			issue.Severity = rule.Severity
+			issues = append(issues, issue)
+		}
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		a, b := issues[i], issues[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		if a.Rule != b.Rule {
+			return a.Rule < b.Rule
+		}
+		return a.Message < b.Message
+	})
+	return issues
+}
+
+// hostIssue makes an issue located at the first definition of the host
+func (inventory *InventoryData) hostIssue(name string, message string) LintIssue {
+	issue := LintIssue{Message: message, Host: name, File: inventory.path}
+	if definitions, ok := lookupName(inventory.hostLines, name, true); ok && len(definitions) > 0 {
+		issue.Line = definitions[0].line
+	}
+	return issue
+}
+
+// groupIssue makes an issue located at the first appearance of the group
+func (inventory *InventoryData) groupIssue(name string, message string) LintIssue {
+	issue := LintIssue{Message: message, Group: name, File: inventory.path}
+	if line, ok := lookupName(inventory.groupLines, name, true); ok {
+		issue.Line = line
+	}
+	return issue
+}
+
+// lintVarsFileErrors reports vars files skipped by AddVars, AddVarsLenient or Load without WithStrict
+func lintVarsFileErrors(inventory *InventoryData) []LintIssue {
+	issues := make([]LintIssue, 0, len(inventory.varsProblems))
+	for _, problem := range inventory.varsProblems {
+		issues = append(issues, LintIssue{Message: problem.Err.Error(), File: problem.Path, Line: problem.Line})
+	}
+	return issues
+}
+
+func lintUnmatchedVarsFiles(inventory *InventoryData) []LintIssue {
+	issues := make([]LintIssue, 0)
+	for _, root := range inventory.varsRoots {
+		for _, subdir := range []string{"group_vars", "host_vars"} {
+			entries, err := os.ReadDir(filepath.Join(root.path, subdir))
+			if err != nil {
+				continue
+			}
+			for _, entry := range entries {
+				name := entry.Name()
+				if strings.HasPrefix(name, ".") {
+					continue
+				}
+				if !entry.IsDir() {
+					if !hasExtension(name, ".yml", ".yaml") {
+						continue
+					}
+					name = strings.TrimSuffix(name, filepath.Ext(name))
+				}
+				var found bool
+				if subdir == "group_vars" {
+					_, found = lookupName(inventory.Groups, name, root.lowercased)
+				} else {
+					_, found = lookupName(inventory.Hosts, name, root.lowercased)
+				}
+				if !found {
+					kind := strings.TrimSuffix(subdir, "_vars")
+					issues = append(issues, LintIssue{
+						Message: fmt.Sprintf("%s/%s matches no %s in inventory", subdir, entry.Name(), kind),
+						File:    filepath.Join(root.path, subdir, entry.Name()),
+					})
+				}
+			}
+		}
+	}
+	return issues
+}
+
+func lintEmptyGroups(inventory *InventoryData) []LintIssue {
+	issues := make([]LintIssue, 0)
+	for _, group := range inventory.Groups {
+		if group.Name == "all" || group.Name == "ungrouped" {
+			continue
+		}
+		if len(group.Hosts) == 0 && len(group.Children) == 0 {
+			issues = append(issues, inventory.groupIssue(group.Name, fmt.Sprintf("group '%s' has no hosts", group.Name)))
+		}
+	}
+	return issues
+}
+
+func lintUngroupedHosts(inventory *InventoryData) []LintIssue {
+	issues := make([]LintIssue, 0)
+	for _, host := range inventory.Hosts {
+		if _, ok := host.DirectGroups["ungrouped"]; ok && len(host.DirectGroups) == 1 {
+			issues = append(issues, inventory.hostIssue(host.Name, fmt.Sprintf("host '%s' is not in any group", host.Name)))
+		}
+	}
+	return issues
+}
+
+var dnsNameRegex = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?(\.[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?)*\.?$`)
+
+func lintInvalidHostnames(inventory *InventoryData) []LintIssue {
+	issues := make([]LintIssue, 0)
+	for _, host := range inventory.Hosts {
+		if net.ParseIP(host.Name) != nil {
+			continue
+		}
+		if len(host.Name) > 253 || !dnsNameRegex.MatchString(host.Name) {
+			issues = append(issues, inventory.hostIssue(host.Name, fmt.Sprintf("host name '%s' is not a valid DNS name or IP address", host.Name)))
+		}
+	}
+	return issues
+}
+
+func lintConflictingHostDefinitions(inventory *InventoryData) []LintIssue {
+	issues := make([]LintIssue, 0)
+	for name, definitions := range inventory.hostLines {
+		first := make(map[string]hostDefinition)
+		for _, definition := range definitions {
+			for _, key := range sortedKeys(definition.vars) {
+				previous, ok := first[key]
+				if !ok {
+					first[key] = definition
+					continue
+				}
+				if previous.vars[key] != definition.vars[key] {
+					issues = append(issues, LintIssue{
+						Message: fmt.Sprintf("host '%s' is defined with %s=%s here and %s=%s on line %d",
+							name, key, definition.vars[key], key, previous.vars[key], previous.line),
+						File: inventory.path,
+						Line: definition.line,
+						Host: name,
+					})
+				}
+			}
+		}
+	}
+	return issues
+}
+
+func lintOverriddenGroupVars(inventory *InventoryData) []LintIssue {
+	issues := make([]LintIssue, 0)
+	for _, group := range inventory.Groups {
+		if len(group.Hosts) == 0 {
+			continue
+		}
+		keys := copyStringMap(group.InventoryVars)
+		addValues(keys, group.FileVars)
+		for _, key := range sortedKeys(keys) {
+			overridden := true
+			for _, host := range group.Hosts {
+				_, inInventory := host.InventoryVars[key]
+				_, inFile := host.FileVars[key]
+				if !inInventory && !inFile {
+					overridden = false
+					break
+				}
+			}
+			if overridden {
+				issues = append(issues, inventory.groupIssue(group.Name,
+					fmt.Sprintf("variable '%s' of group '%s' is overridden by all of its %d host(s)", key, group.Name, len(group.Hosts))))
+			}
+		}
+	}
+	return issues
+}
+
+// ansibleGroupName returns the group name as Ansible would sanitize it, replacing a leading digit and invalid characters
+func ansibleGroupName(name string) string {
+	safe := sanitizeGroupName(name)
+	if safe != "" && safe[0] >= '0' && safe[0] <= '9' {
+		safe = "_" + safe[1:]
+	}
+	return safe
+}
+
+func lintInvalidGroupNames(inventory *InventoryData) []LintIssue {
+	issues := make([]LintIssue, 0)
+	for _, group := range inventory.Groups {
+		if safe := ansibleGroupName(group.Name); safe != group.Name {
+			issues = append(issues, inventory.groupIssue(group.Name,
+				fmt.Sprintf("group name '%s' is not a valid variable name, Ansible would use '%s'", group.Name, safe)))
+		}
+	}
+	return issues
+}
+
+func lintCaseConflicts(inventory *InventoryData) []LintIssue {
+	issues := make([]LintIssue, 0)
+	for _, names := range findCaseConflicts(inventory.Hosts) {
+		issues = append(issues, inventory.hostIssue(names[0], fmt.Sprintf("host names differ only by case: %s", strings.Join(names, ", "))))
+	}
+	for _, names := range findCaseConflicts(inventory.Groups) {
+		issues = append(issues, inventory.groupIssue(names[0], fmt.Sprintf("group names differ only by case: %s", strings.Join(names, ", "))))
+	}
+	return issues
+}
@@ -0,0 +1,127 @@
+package aini
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+)
+
+// WriteLintJSON writes lint issues as an indented JSON array
+func WriteLintJSON(w io.Writer, issues []LintIssue) error {
+	if issues == nil {
+		issues = []LintIssue{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "    ")
+	return encoder.Encode(issues)
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// sarifLevel converts severity to SARIF result level
+func sarifLevel(severity LintSeverity) string {
+	if severity == LintInfo {
+		return "note"
+	}
+	return string(severity)
+}
+
+// WriteLintSARIF writes lint issues in SARIF 2.1.0 format, as consumed by code scanning tools.
+// The rules are listed as rules of the tool.
+func WriteLintSARIF(w io.Writer, issues []LintIssue, rules []LintRule) error {
+	driver := sarifDriver{
+		Name:           "aini",
+		InformationURI: "https://github.com/relex/aini",
+		Rules:          make([]sarifRule, 0, len(rules)),
+	}
+	for _, rule := range rules {
+		driver.Rules = append(driver.Rules, sarifRule{
+			ID:                   rule.Name,
+			ShortDescription:     sarifMessage{Text: rule.Description},
+			DefaultConfiguration: sarifConfiguration{Level: sarifLevel(rule.Severity)},
+		})
+	}
+	run := sarifRun{Tool: sarifTool{Driver: driver}, Results: make([]sarifResult, 0, len(issues))}
+	for _, issue := range issues {
+		result := sarifResult{
+			RuleID:  issue.Rule,
+			Level:   sarifLevel(issue.Severity),
+			Message: sarifMessage{Text: issue.Message},
+		}
+		if issue.File != "" {
+			location := sarifLocation{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(issue.File)},
+			}}
+			if issue.Line > 0 {
+				location.PhysicalLocation.Region = &sarifRegion{StartLine: issue.Line}
+			}
+			result.Locations = []sarifLocation{location}
+		}
+		run.Results = append(run.Results, result)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "    ")
+	return encoder.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
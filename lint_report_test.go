@@ -0,0 +1,50 @@
+package aini
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteLintJSON(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Nil(t, WriteLintJSON(&buf, nil))
+	assert.Equal(t, "[]\n", buf.String())
+
+	buf.Reset()
+	issues := []LintIssue{{Rule: "empty-group", Severity: LintWarning, Message: "group 'g' has no hosts", File: "inventory", Line: 3, Group: "g"}}
+	assert.Nil(t, WriteLintJSON(&buf, issues))
+	var decoded []LintIssue
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, issues, decoded)
+	assert.Contains(t, buf.String(), `"severity": "warning"`)
+}
+
+func TestWriteLintSARIF(t *testing.T) {
+	rules := []LintRule{{Name: "ungrouped-host", Severity: LintInfo, Description: "host only in ungrouped"}}
+	issues := []LintIssue{
+		{Rule: "ungrouped-host", Severity: LintInfo, Message: "host 'h' is not in any group", File: "dir/inventory", Line: 2},
+		{Rule: "ungrouped-host", Severity: LintInfo, Message: "no location"},
+	}
+	var buf bytes.Buffer
+	assert.Nil(t, WriteLintSARIF(&buf, issues, rules))
+
+	var log map[string]any
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &log))
+	assert.Equal(t, "2.1.0", log["version"])
+	run := log["runs"].([]any)[0].(map[string]any)
+	driver := run["tool"].(map[string]any)["driver"].(map[string]any)
+	assert.Equal(t, "aini", driver["name"])
+	assert.Equal(t, "note", driver["rules"].([]any)[0].(map[string]any)["defaultConfiguration"].(map[string]any)["level"])
+
+	results := run["results"].([]any)
+	assert.Len(t, results, 2)
+	first := results[0].(map[string]any)
+	assert.Equal(t, "note", first["level"])
+	location := first["locations"].([]any)[0].(map[string]any)["physicalLocation"].(map[string]any)
+	assert.Equal(t, "dir/inventory", location["artifactLocation"].(map[string]any)["uri"])
+	assert.Equal(t, float64(2), location["region"].(map[string]any)["startLine"])
+	assert.NotContains(t, results[1].(map[string]any), "locations")
+}
@@ -0,0 +1,135 @@
+package aini
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const lintInventory = `[web-servers]
+host1 a=1 x=2
+bad_host
+[empty]
+[web-servers:vars]
+x=1
+[db]
+host1 a=2 x=2
+Web1
+web1
+[db:vars]
+y=1
+`
+
+func lintIssuesByRule(issues []LintIssue) map[string][]LintIssue {
+	result := make(map[string][]LintIssue)
+	for _, issue := range issues {
+		result[issue.Rule] = append(result[issue.Rule], issue)
+	}
+	return result
+}
+
+func TestLint(t *testing.T) {
+	root := writeVarsFiles(t, map[string]string{
+		"inventory":              lintInventory,
+		"group_vars/db.yml":      "z: 1\n",
+		"group_vars/nosuch.yml":  "z: 1\n",
+		"host_vars/ghost/a.yml":  "z: 1\n",
+		"host_vars/README.md":    "not a vars file\n",
+		"host_vars/.hidden.yml":  "z: 1\n",
+		"host_vars/bad_host.yml": "x: 3\n",
+	})
+	path := filepath.Join(root, "inventory")
+	v, err := ParseFile(path)
+	assert.Nil(t, err)
+	assert.Nil(t, v.AddVars(root))
+
+	issues := lintIssuesByRule(Lint(v, DefaultLintRules()))
+
+	assert.Len(t, issues["unmatched-vars-file"], 2)
+	assert.Equal(t, filepath.Join(root, "group_vars", "nosuch.yml"), issues["unmatched-vars-file"][0].File)
+	assert.Equal(t, filepath.Join(root, "host_vars", "ghost"), issues["unmatched-vars-file"][1].File)
+
+	assert.Equal(t, []LintIssue{{Rule: "empty-group", Severity: LintWarning, Message: "group 'empty' has no hosts", File: path, Line: 4, Group: "empty"}}, issues["empty-group"])
+
+	assert.Len(t, issues["invalid-hostname"], 1)
+	assert.Equal(t, "bad_host", issues["invalid-hostname"][0].Host)
+	assert.Equal(t, 3, issues["invalid-hostname"][0].Line)
+	assert.Equal(t, LintError, issues["invalid-hostname"][0].Severity)
+
+	assert.Len(t, issues["conflicting-host-definitions"], 1)
+	assert.Equal(t, 8, issues["conflicting-host-definitions"][0].Line)
+	assert.Equal(t, "host 'host1' is defined with a=2 here and a=1 on line 2", issues["conflicting-host-definitions"][0].Message)
+
+	assert.Len(t, issues["overridden-group-var"], 1)
+	assert.Equal(t, "variable 'x' of group 'web-servers' is overridden by all of its 2 host(s)", issues["overridden-group-var"][0].Message)
+
+	assert.Len(t, issues["invalid-group-name"], 1)
+	assert.Equal(t, 1, issues["invalid-group-name"][0].Line)
+
+	assert.Len(t, issues["case-conflict"], 1)
+	assert.Equal(t, "host names differ only by case: Web1, web1", issues["case-conflict"][0].Message)
+
+	assert.Empty(t, issues["ungrouped-host"])
+}
+
+func TestLintLowerCased(t *testing.T) {
+	v, err := ParseFile("test_data/inventory")
+	assert.Nil(t, err)
+	v.HostsToLower()
+	v.GroupsToLower()
+	assert.Nil(t, v.AddVarsLowerCased("test_data"))
+
+	rule, ok := LintRuleByName("unmatched-vars-file")
+	assert.True(t, ok)
+	issues := Lint(v, []LintRule{rule})
+	// only the "empty" directories don't match
+	assert.Len(t, issues, 2)
+
+	v = parseString(t, `
+	host1
+	[group1]
+	host2
+	`)
+	rule, _ = LintRuleByName("ungrouped-host")
+	issues = Lint(v, []LintRule{rule})
+	assert.Len(t, issues, 1)
+	assert.Equal(t, "host1", issues[0].Host)
+	assert.Equal(t, LintInfo, issues[0].Severity)
+	assert.Equal(t, "", issues[0].File)
+	assert.Equal(t, 2, issues[0].Line)
+
+	_, ok = LintRuleByName("nosuchrule")
+	assert.False(t, ok)
+}
+
+func TestLintCustomRule(t *testing.T) {
+	v := parseString(t, "host1\n")
+	rule := LintRule{Name: "custom", Severity: LintError, Check: func(inventory *InventoryData) []LintIssue {
+		return []LintIssue{{Message: "found", Severity: LintInfo}}
+	}}
+	assert.Equal(t, []LintIssue{{Rule: "custom", Severity: LintError, Message: "found"}}, Lint(v, []LintRule{rule}))
+}
+
+func TestLintVarsFileErrors(t *testing.T) {
+	root := writeVarsFiles(t, map[string]string{
+		"inventory":           "[web]\nhost1\n[empty]\n",
+		"group_vars/web.yml":  "key: [unclosed\n",
+		"host_vars/host1.yml": "x: 1\nx: 2\n",
+	})
+	v, err := Load(filepath.Join(root, "inventory"))
+	assert.Nil(t, err)
+
+	issues := v.Lint(DefaultLintRules())
+	errorIssues := lintIssuesByRule(issues)["vars-file-error"]
+	assert.Len(t, errorIssues, 2)
+	assert.Equal(t, LintError, errorIssues[0].Severity)
+	assert.Equal(t, filepath.Join(root, "group_vars", "web.yml"), errorIssues[0].File)
+	assert.Equal(t, filepath.Join(root, "host_vars", "host1.yml"), errorIssues[1].File)
+	assert.Equal(t, 2, errorIssues[1].Line)
+	// sorted with issues of other rules
+	for i := 1; i < len(issues); i++ {
+		assert.LessOrEqual(t, issues[i-1].File, issues[i].File)
+	}
+	assert.Equal(t, "empty-group", issues[len(issues)-1].Rule)
+}
@@ -0,0 +1,290 @@
+package aini
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+)
+
+// Option configures Load
+type Option func(*loadOptions)
+
+type loadOptions struct {
+	varsRoots       []string
+	varsRootSet     bool
+	lowercase       bool
+	caseInsensitive bool
+	defaultPort     int
+	sources         []string
+	parse           ParseOptions
+	vars            varsOptions
+}
+
+// WithVarsRoots sets directories containing group_vars and host_vars to load variables from.
+// By default variables are loaded from the directory of the inventory file. No directories means no variables.
+func WithVarsRoots(paths ...string) Option {
+	return func(options *loadOptions) {
+		options.varsRoots = paths
+		options.varsRootSet = true
+	}
+}
+
+// WithLowercaseNames converts host and group names to lowercase, as HostsToLower and GroupsToLower
+func WithLowercaseNames() Option {
+	return func(options *loadOptions) {
+		options.lowercase = true
+	}
+}
+
+// WithCaseInsensitiveNames makes lookup and matching of host and group names case-insensitive while keeping
+// their original case, as SetCaseInsensitive. Loading fails if names differ only by case.
+func WithCaseInsensitiveNames() Option {
+	return func(options *loadOptions) {
+		options.caseInsensitive = true
+	}
+}
+
+// WithDefaultPort sets port of hosts without port specified on host lines, instead of 22,
+// as returned by Inventory.HostPort and Inventory.HostConnection
+func WithDefaultPort(port int) Option {
+	return func(options *loadOptions) {
+		options.defaultPort = port
+	}
+}
+
+// WithStrict makes errors in reading and parsing of variable files fail the loading with VarsLoadError,
+// instead of skipping the files and reporting them by Inventory.Warnings
+func WithStrict() Option {
+	return func(options *loadOptions) {
+		options.vars.strict = true
+	}
+}
+
+// WithVault sets passwords to decrypt vaulted variable files and values, tried in order
+func WithVault(passwords ...string) Option {
+	return func(options *loadOptions) {
+		options.vars.vaultPasswords = append(options.vars.vaultPasswords, passwords...)
+	}
+}
+
+// WithSources sets names of inventory sources to try in order, DefaultSourceOrder by default
+func WithSources(names ...string) Option {
+	return func(options *loadOptions) {
+		options.sources = names
+	}
+}
+
+// WithParseOptions sets limits for parsing of untrusted inventories, applied to all inventory sources
+func WithParseOptions(parseOptions ParseOptions) Option {
+	return func(options *loadOptions) {
+		options.parse = parseOptions
+	}
+}
+
+// Inventory is a loaded inventory or Snapshot which cannot be modified, safe for concurrent use.
+// All methods return copies of the underlying data.
+type Inventory struct {
+	data     *InventoryData
+	warnings []*VarsFileError
+	// defaultPort is the port of hosts without port on host lines, set by WithDefaultPort; 0 means 22
+	defaultPort int
+}
+
+// Load parses the inventory file or host list and loads variables for it, as configured by options.
+//
+// Loading is done in order: parse by inventory sources, lowercase names, load group_vars and host_vars, reconcile.
+func Load(path string, options ...Option) (*Inventory, error) {
+	return LoadContext(context.Background(), path, options...)
+}
+
+// LoadContext is Load with cancellation
+func LoadContext(ctx context.Context, path string, options ...Option) (*Inventory, error) {
+	opts := &loadOptions{}
+	for _, option := range options {
+		option(opts)
+	}
+	if !opts.varsRootSet && !IsHostList(path) {
+		opts.varsRoots = []string{filepath.Dir(path)}
+	}
+	opts.vars.lowercased = opts.lowercase
+
+	data, err := ParseFileAutoWithOptions(ctx, path, opts.parse, opts.sources...)
+	if err != nil {
+		return nil, err
+	}
+	if opts.lowercase {
+		data.HostsToLower()
+		data.GroupsToLower()
+	}
+	if opts.caseInsensitive {
+		if err := data.SetCaseInsensitive(); err != nil {
+			return nil, err
+		}
+	}
+	var warnings []*VarsFileError
+	for _, root := range opts.varsRoots {
+		problems, err := data.loadVars(root, opts.vars)
+		if err != nil {
+			return nil, err
+		}
+		warnings = append(warnings, problems...)
+	}
+	if opts.vars.strict && len(warnings) > 0 {
+		return nil, &VarsLoadError{Errors: warnings}
+	}
+	data.Reconcile()
+	return &Inventory{data: data, warnings: warnings, defaultPort: opts.defaultPort}, nil
+}
+
+// Warnings returns problems of variable files which were skipped during loading without WithStrict
+func (inventory *Inventory) Warnings() []*VarsFileError {
+	return append([]*VarsFileError(nil), inventory.warnings...)
+}
+
+// HostNames returns names of all hosts in lexical order
+func (inventory *Inventory) HostNames() []string {
+	return sortedKeys(inventory.data.Hosts)
+}
+
+// GroupNames returns names of all groups in lexical order
+func (inventory *Inventory) GroupNames() []string {
+	return sortedKeys(inventory.data.Groups)
+}
+
+// HasHost checks whether the host exists
+func (inventory *Inventory) HasHost(name string) bool {
+	_, ok := inventory.data.LookupHost(name)
+	return ok
+}
+
+// HasGroup checks whether the group exists
+func (inventory *Inventory) HasGroup(name string) bool {
+	_, ok := inventory.data.LookupGroup(name)
+	return ok
+}
+
+// HostVars returns variables of the host, nil if the host doesn't exist
+func (inventory *Inventory) HostVars(name string) map[string]string {
+	if host, ok := inventory.data.LookupHost(name); ok {
+		return copyStringMap(host.Vars)
+	}
+	return nil
+}
+
+// HostPort returns the port of the host from the host line or the default port
+func (inventory *Inventory) HostPort(name string) (int, bool) {
+	if host, ok := inventory.data.LookupHost(name); ok {
+		if host.portSet || (host.Port != 0 && host.Port != defaultPort) {
+			return host.Port, true
+		}
+		return inventory.hostDefaultPort(), true
+	}
+	return 0, false
+}
+
+// hostDefaultPort returns the port of hosts without port on host lines
+func (inventory *Inventory) hostDefaultPort() int {
+	if inventory.defaultPort != 0 {
+		return inventory.defaultPort
+	}
+	return defaultPort
+}
+
+// HostGroups returns names of all groups of the host in level order, as Host.ListGroupsOrdered
+func (inventory *Inventory) HostGroups(name string) []string {
+	if host, ok := inventory.data.LookupHost(name); ok {
+		return groupNames(host.ListGroupsOrdered())
+	}
+	return nil
+}
+
+// HostConnection resolves connection parameters of the host, as Host.ConnectionWithDefaultPort with the port
+// set by WithDefaultPort
+func (inventory *Inventory) HostConnection(name string) (Connection, bool, error) {
+	if host, ok := inventory.data.LookupHost(name); ok {
+		conn, err := host.ConnectionWithDefaultPort(inventory.hostDefaultPort())
+		return conn, true, err
+	}
+	return Connection{}, false, nil
+}
+
+// HostMagicVars returns Ansible magic variables of the host, as InventoryData.MagicVars but without hostvars,
+// which are available by HostVars
+func (inventory *Inventory) HostMagicVars(name string) (MagicVars, bool) {
+	if host, ok := inventory.data.LookupHost(name); ok {
+		return inventory.data.MagicVarsFunc(MagicVarsOptions{SkipHostVars: true})(host), true
+	}
+	return MagicVars{}, false
+}
+
+// GroupVars returns variables of the group, nil if the group doesn't exist
+func (inventory *Inventory) GroupVars(name string) map[string]string {
+	if group, ok := inventory.data.LookupGroup(name); ok {
+		return copyStringMap(group.Vars)
+	}
+	return nil
+}
+
+// GroupHosts returns names of all hosts in the group including descendant groups, in lexical order
+func (inventory *Inventory) GroupHosts(name string) []string {
+	if group, ok := inventory.data.LookupGroup(name); ok {
+		return sortedKeys(group.Hosts)
+	}
+	return nil
+}
+
+// GroupParents returns names of all ancestor groups of the group in level order, as Group.ListParentGroupsOrdered
+func (inventory *Inventory) GroupParents(name string) []string {
+	if group, ok := inventory.data.LookupGroup(name); ok {
+		return groupNames(group.ListParentGroupsOrdered())
+	}
+	return nil
+}
+
+// GroupChildren returns names of all descendant groups of the group in lexical order
+func (inventory *Inventory) GroupChildren(name string) []string {
+	if group, ok := inventory.data.LookupGroup(name); ok {
+		return sortedKeys(group.Children)
+	}
+	return nil
+}
+
+// MatchHostsByPatterns returns names of hosts matching Ansible host patterns in lexical order, as InventoryData.MatchHostsByPatterns
+func (inventory *Inventory) MatchHostsByPatterns(patterns string) ([]string, error) {
+	hosts, err := inventory.data.MatchHostsByPatterns(patterns)
+	if err != nil {
+		return nil, err
+	}
+	return sortedKeys(hosts), nil
+}
+
+// QueryHosts returns names of hosts matching the query in lexical order, as InventoryData.QueryHosts
+func (inventory *Inventory) QueryHosts(query *Query) ([]string, error) {
+	hosts, err := inventory.data.QueryHosts(query)
+	if err != nil {
+		return nil, err
+	}
+	return sortedKeys(hosts), nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func groupNames(groups []*Group) []string {
+	names := make([]string, 0, len(groups))
+	for _, group := range groups {
+		names = append(names, group.Name)
+	}
+	return names
+}
+
+// Lint checks the inventory by the given rules, as Lint. Rules must not modify the inventory.
+func (inventory *Inventory) Lint(rules []LintRule) []LintIssue {
+	return Lint(inventory.data, rules)
+}
@@ -0,0 +1,128 @@
+package aini
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoad(t *testing.T) {
+	v, err := Load("test_data/inventory")
+	assert.Nil(t, err)
+	assert.True(t, v.HasHost("Host7"))
+	assert.True(t, v.HasGroup("TomCat"))
+	assert.Equal(t, "string", v.HostVars("host1")["host1_string_var"])
+	assert.NotContains(t, v.HostVars("Host7"), "host7_string_var")
+	assert.Nil(t, v.HostVars("nosuchhost"))
+	assert.Equal(t, []string{"nginx", "web", "all"}, v.HostGroups("host1"))
+	assert.Equal(t, []string{"apache", "nginx"}, v.GroupChildren("web"))
+	assert.Equal(t, []string{"host1", "host2", "host3", "host4", "host5", "host6"}, v.GroupHosts("web"))
+	assert.Equal(t, []string{"web", "all"}, v.GroupParents("nginx"))
+
+	v, err = Load("test_data/inventory", WithLowercaseNames(), WithDefaultPort(2222))
+	assert.Nil(t, err)
+	assert.False(t, v.HasHost("Host7"))
+	assert.Equal(t, "string", v.HostVars("host7")["host7_string_var"])
+	assert.Equal(t, "string", v.GroupVars("tomcat")["tomcat_string_var"])
+	port, ok := v.HostPort("host1")
+	assert.True(t, ok)
+	assert.Equal(t, 2222, port)
+
+	// returned maps are copies
+	v.HostVars("host1")["host1_string_var"] = "changed"
+	assert.Equal(t, "string", v.HostVars("host1")["host1_string_var"])
+
+	v, err = Load("test_data/inventory", WithVarsRoots())
+	assert.Nil(t, err)
+	assert.Equal(t, "should be overwritten", v.HostVars("host1")["host1_string_var"])
+
+	hosts, err := v.MatchHostsByPatterns("web:&nginx")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"host1", "host3", "host4"}, hosts)
+
+	v, err = Load("test_data/sources/inventory.yml", WithSources("yaml"))
+	assert.Nil(t, err)
+	conn, ok, err := v.HostConnection("gateway")
+	assert.True(t, ok)
+	assert.Nil(t, err)
+	assert.Equal(t, "10.0.0.1", conn.Host.Value)
+
+	_, err = Load("test_data/inventory", WithSources("yaml"))
+	assert.NotNil(t, err)
+
+	v, err = Load("web1,web2:2200,", WithDefaultPort(2222))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"web1", "web2"}, v.GroupHosts("ungrouped"))
+	port, _ = v.HostPort("web2")
+	assert.Equal(t, 2200, port)
+}
+
+func TestLoadDefaultPortConnection(t *testing.T) {
+	v, err := Load("web1,web2:2200,web3:22,", WithDefaultPort(2222))
+	assert.Nil(t, err)
+
+	conn, ok, err := v.HostConnection("web1")
+	assert.True(t, ok)
+	assert.Nil(t, err)
+	assert.Equal(t, ConnectionParam[int]{Value: 2222}, conn.Port)
+	port, _ := v.HostPort("web1")
+	assert.Equal(t, 2222, port)
+
+	conn, _, _ = v.HostConnection("web2")
+	assert.Equal(t, ConnectionParam[int]{Value: 2200, Explicit: true}, conn.Port)
+	conn, _, _ = v.HostConnection("web3")
+	assert.Equal(t, ConnectionParam[int]{Value: 22, Explicit: true}, conn.Port)
+	port, _ = v.HostPort("web3")
+	assert.Equal(t, 22, port)
+
+	v, err = Load("web1,")
+	assert.Nil(t, err)
+	conn, _, _ = v.HostConnection("web1")
+	assert.Equal(t, ConnectionParam[int]{Value: 22}, conn.Port)
+}
+
+func TestLoadStrict(t *testing.T) {
+	root := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(root, "inventory"), []byte("[web]\nhost1\n"), 0o644))
+	assert.Nil(t, os.MkdirAll(filepath.Join(root, "group_vars"), 0o755))
+	assert.Nil(t, os.WriteFile(filepath.Join(root, "group_vars", "web.yml"), []byte("key: [unclosed\n"), 0o644))
+
+	v, err := Load(filepath.Join(root, "inventory"))
+	assert.Nil(t, err)
+	assert.Len(t, v.Warnings(), 1)
+
+	_, err = Load(filepath.Join(root, "inventory"), WithStrict())
+	var loadErr *VarsLoadError
+	assert.True(t, errors.As(err, &loadErr))
+
+	_, err = Load(filepath.Join(root, "inventory"), WithVault("pass"), WithStrict(), WithVarsRoots(filepath.Join(root, "nosuchdir")))
+	assert.NotNil(t, err)
+}
+
+func TestLoadWithParseOptions(t *testing.T) {
+	for path, hosts := range map[string]int{
+		"test_data/sources/inventory.ini":  2,
+		"test_data/sources/inventory.yml":  4,
+		"test_data/sources/inventory.json": 3,
+		"web01,web02,web03,gateway":        4,
+	} {
+		_, err := Load(path, WithParseOptions(ParseOptions{MaxHosts: hosts - 1}))
+		assert.True(t, errors.Is(err, ErrLimitExceeded), path)
+
+		v, err := Load(path, WithParseOptions(ParseOptions{MaxHosts: hosts}))
+		assert.Nil(t, err, path)
+		assert.Len(t, v.HostNames(), hosts, path)
+	}
+
+	_, err := Load("test_data/sources/inventory.yml", WithParseOptions(ParseOptions{MaxHostsPerLine: 1}))
+	assert.True(t, errors.Is(err, ErrLimitExceeded))
+	_, err = Load("web[01:99]", WithParseOptions(ParseOptions{MaxHostsPerLine: 10}))
+	assert.NotNil(t, err)
+	_, err = Load("web[01:99],", WithParseOptions(ParseOptions{MaxHostsPerLine: 10}))
+	assert.True(t, errors.Is(err, ErrLimitExceeded))
+	_, err = Load("test_data/sources/inventory.json", WithParseOptions(ParseOptions{MaxLineLength: 40}))
+	assert.True(t, errors.Is(err, ErrLimitExceeded))
+}
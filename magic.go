@@ -0,0 +1,99 @@
+package aini
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// MagicVars are Ansible magic variables of a host derived from the inventory,
+// see https://docs.ansible.com/ansible/latest/reference_appendices/special_variables.html
+type MagicVars struct {
+	InventoryHostname      string `json:"inventory_hostname"`
+	InventoryHostnameShort string `json:"inventory_hostname_short"`
+	// GroupNames are all groups of the host except all and ungrouped, in lexical order
+	GroupNames []string `json:"group_names"`
+	// Groups are names of all groups with names of their hosts in lexical order
+	Groups map[string][]string `json:"groups"`
+	// InventoryDir and InventoryFile are the absolute path of the parsed inventory file and its directory,
+	// empty if the inventory was not parsed from a file
+	InventoryDir  string `json:"inventory_dir"`
+	InventoryFile string `json:"inventory_file"`
+	// HostVars are variables of all hosts by host names
+	HostVars map[string]map[string]string `json:"hostvars,omitempty"`
+}
+
+// MagicVars returns magic variables of the host of the inventory.
+// To get magic variables of many hosts, use MagicVarsFunc, which builds groups and hostvars only once.
+func (inventory *InventoryData) MagicVars(host *Host) MagicVars {
+	return inventory.MagicVarsFunc(MagicVarsOptions{})(host)
+}
+
+// MagicVarsOptions select magic variables to compute
+type MagicVarsOptions struct {
+	// SkipHostVars leaves HostVars nil, as it holds variables of all hosts of the inventory
+	SkipHostVars bool
+}
+
+// MagicVarsFunc returns a function computing magic variables of hosts of the inventory.
+// Groups and HostVars are built once by the call and shared by magic variables of all hosts, so they must not be modified.
+func (inventory *InventoryData) MagicVarsFunc(options MagicVarsOptions) func(host *Host) MagicVars {
+	groups := make(map[string][]string, len(inventory.Groups))
+	for name, group := range inventory.Groups {
+		groups[name] = sortedKeys(group.Hosts)
+	}
+	var hostVars map[string]map[string]string
+	if !options.SkipHostVars {
+		hostVars = make(map[string]map[string]string, len(inventory.Hosts))
+		for name, host := range inventory.Hosts {
+			hostVars[name] = copyStringMap(host.Vars)
+		}
+	}
+	var inventoryFile, inventoryDir string
+	if inventory.path != "" && !IsHostList(inventory.path) {
+		if path, err := filepath.Abs(inventory.path); err == nil {
+			inventoryFile = path
+			inventoryDir = filepath.Dir(path)
+		}
+	}
+	return func(host *Host) MagicVars {
+		return MagicVars{
+			InventoryHostname:      host.Name,
+			InventoryHostnameShort: host.shortName(),
+			GroupNames:             host.magicGroupNames(),
+			Groups:                 groups,
+			InventoryDir:           inventoryDir,
+			InventoryFile:          inventoryFile,
+			HostVars:               hostVars,
+		}
+	}
+}
+
+// Map returns the magic variables by their names in Ansible, e.g. for templates
+func (magic MagicVars) Map() map[string]any {
+	return map[string]any{
+		"inventory_hostname":       magic.InventoryHostname,
+		"inventory_hostname_short": magic.InventoryHostnameShort,
+		"group_names":              magic.GroupNames,
+		"groups":                   magic.Groups,
+		"inventory_dir":            magic.InventoryDir,
+		"inventory_file":           magic.InventoryFile,
+		"hostvars":                 magic.HostVars,
+	}
+}
+
+// shortName returns the host name up to the first dot, as inventory_hostname_short
+func (host *Host) shortName() string {
+	short, _, _ := strings.Cut(host.Name, ".")
+	return short
+}
+
+// magicGroupNames returns names of all groups of the host except all and ungrouped in lexical order, as group_names
+func (host *Host) magicGroupNames() []string {
+	names := make([]string, 0, len(host.Groups))
+	for _, name := range sortedKeys(host.Groups) {
+		if name != "all" && name != "ungrouped" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,77 @@
+package aini
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMagicVars(t *testing.T) {
+	v := parseString(t, `
+	lonely.example.com
+	[web]
+	web1.example.com env=prod
+	[eu:children]
+	web
+	`)
+	magic := v.MagicVars(v.Hosts["web1.example.com"])
+	assert.Equal(t, "web1.example.com", magic.InventoryHostname)
+	assert.Equal(t, "web1", magic.InventoryHostnameShort)
+	assert.Equal(t, []string{"eu", "web"}, magic.GroupNames)
+	assert.Equal(t, map[string][]string{
+		"all":       {"lonely.example.com", "web1.example.com"},
+		"eu":        {"web1.example.com"},
+		"ungrouped": {"lonely.example.com"},
+		"web":       {"web1.example.com"},
+	}, magic.Groups)
+	assert.Equal(t, "prod", magic.HostVars["web1.example.com"]["env"])
+	assert.Equal(t, "", magic.InventoryFile)
+
+	assert.Empty(t, v.MagicVars(v.Hosts["lonely.example.com"]).GroupNames)
+	assert.Equal(t, []string{"eu", "web"}, magic.Map()["group_names"])
+	assert.Equal(t, "web1", magic.Map()["inventory_hostname_short"])
+
+	// copies are returned
+	magic.HostVars["web1.example.com"]["env"] = "changed"
+	assert.Equal(t, "prod", v.Hosts["web1.example.com"].Vars["env"])
+}
+
+func TestMagicVarsFunc(t *testing.T) {
+	v := parseString(t, `
+	lonely.example.com
+	[web]
+	web1.example.com env=prod
+	web2.example.com
+	`)
+	magicVars := v.MagicVarsFunc(MagicVarsOptions{SkipHostVars: true})
+	web1 := magicVars(v.Hosts["web1.example.com"])
+	web2 := magicVars(v.Hosts["web2.example.com"])
+	assert.Equal(t, v.MagicVars(v.Hosts["web1.example.com"]).Groups, web1.Groups)
+	assert.Equal(t, []string{"web"}, web2.GroupNames)
+	assert.Equal(t, "web2.example.com", web2.InventoryHostname)
+	assert.Nil(t, web1.HostVars)
+
+	// groups are built once and shared by all hosts
+	web1.Groups["web"] = nil
+	assert.Nil(t, web2.Groups["web"])
+
+	magicVars = v.MagicVarsFunc(MagicVarsOptions{})
+	assert.Equal(t, "prod", magicVars(v.Hosts["web2.example.com"]).HostVars["web1.example.com"]["env"])
+}
+
+func TestHostMagicVars(t *testing.T) {
+	v, err := Load("test_data/inventory")
+	assert.Nil(t, err)
+	magic, ok := v.HostMagicVars("host1")
+	assert.True(t, ok)
+	path, _ := filepath.Abs("test_data/inventory")
+	assert.Equal(t, path, magic.InventoryFile)
+	assert.Equal(t, filepath.Dir(path), magic.InventoryDir)
+	assert.Equal(t, []string{"nginx", "web"}, magic.GroupNames)
+	assert.Equal(t, []string{"host1", "host2", "host3", "host4", "host5", "host6"}, magic.Groups["web"])
+	assert.Nil(t, magic.HostVars)
+
+	_, ok = v.HostMagicVars("nosuchhost")
+	assert.False(t, ok)
+}
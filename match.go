@@ -1,110 +1,67 @@
 package aini
 
 import (
-	"fmt"
 	"path"
 	"strings"
-
-	"golang.org/x/exp/maps"
 )
 
 // MatchHostsByPatterns looks for all hosts that match the Ansible host patterns as described in https://docs.ansible.com/ansible/latest/inventory_guide/intro_patterns.html
 //
 // e.g. "webservers:gateways:myhost.domain:!atlanta"
+//
+// Use CompilePattern to match the same patterns many times.
 func (inventory *InventoryData) MatchHostsByPatterns(patterns string) (map[string]*Host, error) {
-	patternList := strings.Split(patterns, ":")
+	pattern, err := CompilePattern(patterns)
+	if err != nil {
+		return make(map[string]*Host), err
+	}
+	return pattern.MatchHosts(inventory), nil
+}
 
-	matchedHosts := make(map[string]*Host)
-	for _, host := range inventory.Hosts {
-		matched, err := host.MatchPatterns(patternList)
-		if err != nil {
-			return matchedHosts, err
-		}
-		if matched {
-			matchedHosts[host.Name] = host
-		}
+// MatchHostSetByPatterns looks for all hosts that match the Ansible host patterns like MatchHostsByPatterns, returning a HostSet
+func (inventory *InventoryData) MatchHostSetByPatterns(patterns string) (*HostSet, error) {
+	hosts, err := inventory.MatchHostsByPatterns(patterns)
+	if err != nil {
+		return nil, err
 	}
-	return matchedHosts, nil
+	return inventory.HostSet(hosts), nil
 }
 
 // MatchPatterns checks whether the given host matches the list of Ansible host patterns.
 //
 // e.g. [webservers, gateways, myhost.domain, !atlanta]
 func (host *Host) MatchPatterns(patterns []string) (bool, error) {
-	allNames := make([]string, 0, 1+len(host.Groups))
-	allNames = append(allNames, host.Name)
-	allNames = append(allNames, maps.Keys(host.Groups)...)
-	return MatchNamesByPatterns(allNames, patterns)
+	return MatchNamesByPatterns(hostAndGroupNames(host), patterns)
 }
 
 // MatchNamesByPatterns checks whether the give hostname and group names match list of Ansible host patterns.
 //
 // e.g. [webservers, gateways, myhost.domain, !atlanta]
 func MatchNamesByPatterns(allNames []string, patterns []string) (bool, error) {
-	numPositiveMatch := 0
-
-	for index, pattern := range patterns {
-		switch {
-		case pattern == "":
-			if index == 0 {
-				return false, nil
-			}
-			continue
-		case pattern == "all" || pattern == "*":
-			numPositiveMatch++
-		case pattern[0] == '!':
-			if index == 0 {
-				return false, fmt.Errorf("exclusion pattern \"%s\" cannot be the first pattern", pattern)
-			}
-			any, err := matchAnyName(pattern[1:], allNames)
-			if err != nil {
-				return false, err
-			}
-			if any {
-				return false, err
-			}
-		case pattern[0] == '&':
-			if index == 0 {
-				return false, fmt.Errorf("intersection pattern \"%s\" cannot be the first pattern", pattern)
-			}
-			any, err := matchAnyName(pattern[1:], allNames)
-			if err != nil {
-				return false, err
-			}
-			if !any {
-				return false, err
-			}
-		default:
-			any, err := matchAnyName(pattern, allNames)
-			if err != nil {
-				return false, err
-			}
-			if any {
-				numPositiveMatch++
-			}
-		}
+	pattern, err := compilePatternList(strings.Join(patterns, ":"), patterns)
+	if err != nil {
+		return false, err
 	}
-	return numPositiveMatch > 0, nil
-}
-
-func matchAnyName(pattern string, allNames []string) (bool, error) {
-	for _, name := range allNames {
-		matched, err := path.Match(pattern, name)
-		if err != nil {
-			return false, err
-		}
-		if matched {
-			return true, nil
-		}
-	}
-	return false, nil
+	return pattern.MatchNames(allNames), nil
 }
 
 // MatchHosts looks for hosts whose hostnames match the pattern. Group memberships are not considered.
 func (inventory *InventoryData) MatchHosts(pattern string) (map[string]*Host, error) {
+	if inventory.caseInsensitive {
+		return MatchHostsCaseInsensitive(inventory.Hosts, pattern)
+	}
 	return MatchHosts(inventory.Hosts, pattern)
 }
 
+// MatchHostSet looks for hosts whose hostnames match the pattern like MatchHosts, returning a HostSet
+func (inventory *InventoryData) MatchHostSet(pattern string) (*HostSet, error) {
+	hosts, err := inventory.MatchHosts(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return inventory.HostSet(hosts), nil
+}
+
 // MatchHosts looks for hosts whose hostnames match the pattern. Group memberships are not considered.
 func (group *Group) MatchHosts(pattern string) (map[string]*Host, error) {
 	return MatchHosts(group.Hosts, pattern)
@@ -127,9 +84,21 @@ func MatchHosts(hosts map[string]*Host, pattern string) (map[string]*Host, error
 
 // MatchGroups looks for groups that match the pattern
 func (inventory *InventoryData) MatchGroups(pattern string) (map[string]*Group, error) {
+	if inventory.caseInsensitive {
+		return MatchGroupsCaseInsensitive(inventory.Groups, pattern)
+	}
 	return MatchGroups(inventory.Groups, pattern)
 }
 
+// MatchGroupSet looks for groups that match the pattern like MatchGroups, returning a GroupSet
+func (inventory *InventoryData) MatchGroupSet(pattern string) (*GroupSet, error) {
+	groups, err := inventory.MatchGroups(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return inventory.GroupSet(groups), nil
+}
+
 // MatchGroups looks for groups that match the pattern
 func (host *Host) MatchGroups(pattern string) (map[string]*Group, error) {
 	return MatchGroups(host.Groups, pattern)
@@ -0,0 +1,167 @@
+package aini
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MergePolicy decides which value is kept when merged inventories define different values
+type MergePolicy int
+
+const (
+	// MergeLastWins keeps values of later inventories, as Ansible does with multiple inventory sources
+	MergeLastWins MergePolicy = iota
+	// MergeFirstWins keeps values of earlier inventories
+	MergeFirstWins
+	// MergeError fails merging on any conflict, leaving the inventory unchanged
+	MergeError
+)
+
+// MergeOptions controls InventoryData.Merge
+type MergeOptions struct {
+	Policy MergePolicy
+}
+
+// MergeConflict is a port or variable defined differently by the inventory and a merged source
+type MergeConflict struct {
+	// Source is the index of the merged inventory in sources of Merge
+	Source int
+	// Host or Group is the name of the host or group defined differently
+	Host  string
+	Group string
+	// Kind is one of "port", "inventory var" and "file var"
+	Kind string
+	// Var is the name of the conflicting variable, empty for ports
+	Var string
+	// Existing and Incoming are the value in the merged inventory so far and the value from the source
+	Existing string
+	Incoming string
+}
+
+func (conflict MergeConflict) String() string {
+	subject := "host " + conflict.Host
+	if conflict.Host == "" {
+		subject = "group " + conflict.Group
+	}
+	if conflict.Var != "" {
+		subject += " " + conflict.Kind + " " + conflict.Var
+	} else {
+		subject += " " + conflict.Kind
+	}
+	return fmt.Sprintf("source %d: %s: '%s' differs from '%s'", conflict.Source, subject, conflict.Incoming, conflict.Existing)
+}
+
+// MergeConflictError is returned by Merge with MergeError policy when sources conflict
+type MergeConflictError struct {
+	Conflicts []MergeConflict
+}
+
+func (err *MergeConflictError) Error() string {
+	messages := make([]string, 0, len(err.Conflicts))
+	for _, conflict := range err.Conflicts {
+		messages = append(messages, conflict.String())
+	}
+	return fmt.Sprintf("%d merge conflicts: %s", len(err.Conflicts), strings.Join(messages, "; "))
+}
+
+// Merge adds hosts, groups, group memberships and parents, inventory vars and file vars of the sources to the inventory
+// in order, and reconciles it. Sources are not modified, and locations of their definitions are not merged for Lint.
+//
+// Ports given on host lines and variables defined differently are resolved by the policy, and returned as conflicts.
+// With MergeError the inventory is left unchanged and MergeConflictError returned if there is any conflict.
+func (inventory *InventoryData) Merge(options MergeOptions, sources ...*InventoryData) ([]MergeConflict, error) {
+	if options.Policy == MergeError {
+		conflicts := inventory.Clone().merge(sources, options.Policy)
+		if len(conflicts) > 0 {
+			return conflicts, &MergeConflictError{Conflicts: conflicts}
+		}
+	}
+	return inventory.merge(sources, options.Policy), nil
+}
+
+func (inventory *InventoryData) merge(sources []*InventoryData, policy MergePolicy) []MergeConflict {
+	inventory.init()
+	conflicts := make([]MergeConflict, 0)
+	for index, source := range sources {
+		// groups first, so that hosts are added to groups with parents set
+		for _, name := range sortedKeys(source.Groups) {
+			from := source.Groups[name]
+			group := inventory.getOrCreateGroup(from.Name)
+			report := func(conflict MergeConflict) {
+				conflict.Source, conflict.Group = index, group.Name
+				conflicts = append(conflicts, conflict)
+			}
+			group.InventoryVars = mergeVars(group.InventoryVars, from.InventoryVars, "inventory var", policy, report)
+			group.FileVars = mergeVars(group.FileVars, from.FileVars, "file var", policy, report)
+			for _, parent := range from.DirectParents {
+				group.DirectParents[parent.Name] = inventory.getOrCreateGroup(parent.Name)
+			}
+		}
+		for _, name := range sortedKeys(source.Hosts) {
+			from := source.Hosts[name]
+			host := inventory.getOrCreateHost(from.Name)
+			report := func(conflict MergeConflict) {
+				conflict.Source, conflict.Host = index, host.Name
+				conflicts = append(conflicts, conflict)
+			}
+			if from.portSet {
+				switch {
+				case !host.portSet:
+					host.Port, host.portSet = from.Port, true
+				case host.Port != from.Port:
+					report(MergeConflict{Kind: "port", Existing: fmt.Sprint(host.Port), Incoming: fmt.Sprint(from.Port)})
+					if policy == MergeLastWins {
+						host.Port = from.Port
+					}
+				}
+			}
+			host.InventoryVars = mergeVars(host.InventoryVars, from.InventoryVars, "inventory var", policy, report)
+			host.FileVars = mergeVars(host.FileVars, from.FileVars, "file var", policy, report)
+			for _, group := range from.DirectGroups {
+				// a host is ungrouped only if no inventory puts it into other groups
+				if group.Name == "ungrouped" && len(host.DirectGroups) > 0 {
+					continue
+				}
+				inventory.addHostToGroup(host, inventory.getOrCreateGroup(group.Name))
+			}
+		}
+		for _, root := range source.varsRoots {
+			inventory.addVarsRoot(root)
+		}
+		inventory.varsProblems = append(inventory.varsProblems, source.varsProblems...)
+	}
+	inventory.Reconcile()
+	return conflicts
+}
+
+// mergeVars copies vars from the source, reporting different values of the same names in sorted order.
+// It returns the map of vars, made if nil.
+func mergeVars(to map[string]string, from map[string]string, kind string, policy MergePolicy, report func(MergeConflict)) map[string]string {
+	if to == nil {
+		to = make(map[string]string, len(from))
+	}
+	for _, name := range sortedKeys(from) {
+		value := from[name]
+		existing, ok := to[name]
+		switch {
+		case !ok:
+			to[name] = value
+		case existing != value:
+			report(MergeConflict{Kind: kind, Var: name, Existing: existing, Incoming: value})
+			if policy == MergeLastWins {
+				to[name] = value
+			}
+		}
+	}
+	return to
+}
+
+// addVarsRoot records the directory which vars were loaded from, if not yet
+func (inventory *InventoryData) addVarsRoot(root varsRoot) {
+	for _, existing := range inventory.varsRoots {
+		if existing == root {
+			return
+		}
+	}
+	inventory.varsRoots = append(inventory.varsRoots, root)
+}
@@ -0,0 +1,87 @@
+package aini
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerge(t *testing.T) {
+	base := parseString(t, `
+	lonely
+	[web]
+	web1:2200 env=prod
+	web2
+	[web:vars]
+	tier=1
+	`)
+	overlay := parseString(t, `
+	lonely
+	[eu:children]
+	web
+	[eu]
+	web1:2222 env=staging region=eu
+	[web:vars]
+	tier=2
+	`)
+	generated := parseString(t, `
+	[db]
+	db1 env=prod
+	lonely
+	`)
+	conflicts, err := base.Merge(MergeOptions{}, overlay, generated)
+	assert.Nil(t, err)
+	assert.Equal(t, []MergeConflict{
+		{Source: 0, Group: "web", Kind: "inventory var", Var: "tier", Existing: "1", Incoming: "2"},
+		{Source: 0, Host: "web1", Kind: "port", Existing: "2200", Incoming: "2222"},
+		{Source: 0, Host: "web1", Kind: "inventory var", Var: "env", Existing: "prod", Incoming: "staging"},
+	}, conflicts)
+	assert.Equal(t, "source 0: host web1 inventory var env: 'staging' differs from 'prod'", conflicts[2].String())
+
+	assert.Equal(t, []string{"db1", "lonely", "web1", "web2"}, sortedKeys(base.Hosts))
+	web1 := base.Hosts["web1"]
+	assert.Equal(t, 2222, web1.Port)
+	assert.Equal(t, map[string]string{"env": "staging", "region": "eu", "tier": "2"}, web1.Vars)
+	assert.Equal(t, []string{"all", "eu", "web"}, sortedKeys(web1.Groups))
+	assert.Equal(t, []string{"web1", "web2"}, sortedKeys(base.Groups["eu"].Hosts))
+	// hosts put into groups by any source are no longer ungrouped
+	assert.Equal(t, []string{"db"}, sortedKeys(base.Hosts["lonely"].DirectGroups))
+	assert.Empty(t, base.Groups["ungrouped"].Hosts)
+	// sources are not modified
+	assert.Equal(t, "2", overlay.Groups["web"].Vars["tier"])
+	assert.NotContains(t, overlay.Hosts, "db1")
+	assert.NotSame(t, overlay.Groups["eu"], base.Groups["eu"])
+}
+
+func TestMergePolicies(t *testing.T) {
+	parse := func() (*InventoryData, *InventoryData) {
+		return parseString(t, "host1:2200 env=prod\n"), parseString(t, "host1:2222 env=dev\nhost2\n")
+	}
+
+	first, second := parse()
+	conflicts, err := first.Merge(MergeOptions{Policy: MergeFirstWins}, second)
+	assert.Nil(t, err)
+	assert.Len(t, conflicts, 2)
+	assert.Equal(t, 2200, first.Hosts["host1"].Port)
+	assert.Equal(t, "prod", first.Hosts["host1"].Vars["env"])
+	assert.Contains(t, first.Hosts, "host2")
+
+	first, second = parse()
+	conflicts, err = first.Merge(MergeOptions{Policy: MergeError}, second)
+	var conflictErr *MergeConflictError
+	assert.True(t, errors.As(err, &conflictErr))
+	assert.Equal(t, conflicts, conflictErr.Conflicts)
+	assert.Equal(t, "2 merge conflicts: source 0: host host1 port: '2222' differs from '2200'; "+
+		"source 0: host host1 inventory var env: 'dev' differs from 'prod'", err.Error())
+	// unchanged on error
+	assert.NotContains(t, first.Hosts, "host2")
+	assert.Equal(t, "prod", first.Hosts["host1"].Vars["env"])
+
+	first, _ = parse()
+	conflicts, err = first.Merge(MergeOptions{Policy: MergeError}, parseString(t, "host1 env=prod\nhost3\n"))
+	assert.Nil(t, err)
+	assert.Empty(t, conflicts)
+	assert.Contains(t, first.Hosts, "host3")
+	assert.Equal(t, 2200, first.Hosts["host1"].Port)
+}
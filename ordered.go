@@ -44,23 +44,35 @@ func (group *Group) MatchGroupsOrdered(pattern string) ([]*Group, error) {
 
 // ListGroupsOrdered returns all ancestor groups of a given host in level order
 func (host *Host) ListGroupsOrdered() []*Group {
-	return listAncestorsOrdered(host.DirectGroups, nil, true)
+	return host.ListGroupsOrderedBy(LexicalOrder)
+}
+
+// ListGroupsOrderedBy returns all ancestor groups of a given host in level order,
+// with groups of the same parent sorted in lexical or natural order
+func (host *Host) ListGroupsOrderedBy(order SortOrder) []*Group {
+	return listAncestorsOrdered(host.DirectGroups, nil, true, order)
 }
 
 // ListParentGroupsOrdered returns all ancestor groups of a given group in level order
 func (group *Group) ListParentGroupsOrdered() []*Group {
+	return group.ListParentGroupsOrderedBy(LexicalOrder)
+}
+
+// ListParentGroupsOrderedBy returns all ancestor groups of a given group in level order,
+// with groups of the same child sorted in lexical or natural order
+func (group *Group) ListParentGroupsOrderedBy(order SortOrder) []*Group {
 	visited := map[string]struct{}{group.Name: {}}
-	return listAncestorsOrdered(group.DirectParents, visited, group.Name != "all")
+	return listAncestorsOrdered(group.DirectParents, visited, group.Name != "all", order)
 }
 
 // listAncestorsOrdered returns all ancestor groups of a given group map in level order
-func listAncestorsOrdered(groups map[string]*Group, visited map[string]struct{}, appendAll bool) []*Group {
+func listAncestorsOrdered(groups map[string]*Group, visited map[string]struct{}, appendAll bool, order SortOrder) []*Group {
 	result := make([]*Group, 0)
 	if visited == nil {
 		visited = map[string]struct{}{}
 	}
 	var allGroup *Group
-	for queue := GroupMapListValues(groups); len(queue) > 0; func() {
+	for queue := GroupMapListValuesSorted(groups, order); len(queue) > 0; func() {
 		copy(queue, queue[1:])
 		queue = queue[:len(queue)-1]
 	}() {
@@ -74,7 +86,7 @@ func listAncestorsOrdered(groups map[string]*Group, visited map[string]struct{},
 			continue
 		}
 		visited[group.Name] = struct{}{}
-		parentList := GroupMapListValues(group.DirectParents)
+		parentList := GroupMapListValuesSorted(group.DirectParents, order)
 		result = append(result, group)
 		queue = append(queue, parentList...)
 	}
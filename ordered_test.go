@@ -73,3 +73,23 @@ func TestMatchGroupsOrdered(t *testing.T) {
 	assert.Len(t, groups, 1)
 	assert.Equal(t, groups[0].Name, "myGroup2")
 }
+
+func TestListAncestorsOrderedBy(t *testing.T) {
+	v := parseString(t, `
+	[web10]
+	host1
+	[web9]
+	host1
+	[dc2:children]
+	web9
+	[dc10:children]
+	web10
+	web9
+	`)
+
+	host1 := v.Hosts["host1"]
+	assert.Equal(t, []string{"web10", "web9", "dc10", "dc2", "all"}, getGroupNamesOf(host1.ListGroupsOrdered()))
+	assert.Equal(t, []string{"web9", "web10", "dc2", "dc10", "all"}, getGroupNamesOf(host1.ListGroupsOrderedBy(NaturalOrder)))
+	assert.Equal(t, []string{"dc10", "dc2", "all"}, getGroupNamesOf(v.Groups["web9"].ListParentGroupsOrdered()))
+	assert.Equal(t, []string{"dc2", "dc10", "all"}, getGroupNamesOf(v.Groups["web9"].ListParentGroupsOrderedBy(NaturalOrder)))
+}
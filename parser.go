@@ -2,6 +2,9 @@ package aini
 
 import (
 	"bufio"
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"math"
 	"regexp"
@@ -37,17 +40,76 @@ func getState(str string) (state, bool) {
 
 // state enum end
 
+// ErrLimitExceeded is returned when parsing exceeds limits set in ParseOptions
+var ErrLimitExceeded = errors.New("parse limit exceeded")
+
+// ParseOptions sets limits for parsing of untrusted inventories. Zero values mean no limit, except for MaxLineLength.
+type ParseOptions struct {
+	// MaxHostsPerLine limits the number of hosts a single host line can expand into, e.g. `node[0:9999999]`
+	MaxHostsPerLine int
+	// MaxHosts limits the total number of hosts in the inventory
+	MaxHosts int
+	// MaxLineLength limits the length of lines in bytes, 64KB (bufio.MaxScanTokenSize) if not set.
+	// For YAML and JSON files it's only checked if set, and output of inventory scripts is not limited.
+	MaxLineLength int
+}
+
+// ParseOptions returns limits of the parsing in progress, for InventorySource implementations to honour.
+// MaxHosts is also checked after the source returns.
+func (inventory *InventoryData) ParseOptions() ParseOptions {
+	return inventory.parseOptions
+}
+
+// checkHostLimit fails if the inventory has more hosts than allowed by ParseOptions
+func (inventory *InventoryData) checkHostLimit() error {
+	if max := inventory.parseOptions.MaxHosts; max > 0 && len(inventory.Hosts) > max {
+		return fmt.Errorf("more than %d hosts in inventory: %w", max, ErrLimitExceeded)
+	}
+	return nil
+}
+
+// checkLineLength fails if any line of the data is longer than MaxLineLength of ParseOptions, if set
+func (options ParseOptions) checkLineLength(data []byte) error {
+	if options.MaxLineLength <= 0 {
+		return nil
+	}
+	for lineNumber, line := range bytes.Split(data, []byte("\n")) {
+		if len(line) > options.MaxLineLength {
+			return fmt.Errorf("line %d: longer than %d bytes: %w", lineNumber+1, options.MaxLineLength, ErrLimitExceeded)
+		}
+	}
+	return nil
+}
+
+// expansionLimit returns the maximum number of hosts allowed from a single host line
+func (options ParseOptions) expansionLimit() int {
+	limit := options.MaxHostsPerLine
+	if options.MaxHosts > 0 && (limit == 0 || options.MaxHosts < limit) {
+		limit = options.MaxHosts
+	}
+	return limit
+}
+
 // parser performs parsing of inventory file from some Reader
-func (inventory *InventoryData) parse(reader *bufio.Reader) error {
+func (inventory *InventoryData) parse(ctx context.Context, reader *bufio.Reader, options ParseOptions) error {
 	// This regexp is copy-pasted from ansible sources
 	sectionRegex := regexp.MustCompile(`^\[([^:\]\s]+)(?::(\w+))?\]\s*(?:\#.*)?$`)
 	scanner := bufio.NewScanner(reader)
-	inventory.Groups = make(map[string]*Group)
-	inventory.Hosts = make(map[string]*Host)
+	maxLineLength := options.MaxLineLength
+	if maxLineLength <= 0 {
+		maxLineLength = bufio.MaxScanTokenSize
+	}
+	scanner.Buffer(nil, maxLineLength)
+	inventory.init()
 	activeState := hostsState
 	activeGroup := inventory.getOrCreateGroup("ungrouped")
+	lineNumber := 0
 
 	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		lineNumber++
 		line := strings.TrimSpace(scanner.Text())
 		if strings.HasPrefix(line, ";") || strings.HasPrefix(line, "#") || line == "" {
 			continue
@@ -55,6 +117,7 @@ func (inventory *InventoryData) parse(reader *bufio.Reader) error {
 		matches := sectionRegex.FindAllStringSubmatch(line, -1)
 		if matches != nil {
 			activeGroup = inventory.getOrCreateGroup(matches[0][1])
+			inventory.recordGroupLine(activeGroup.Name, lineNumber)
 			var ok bool
 			if activeState, ok = getState(matches[0][2]); !ok {
 				return fmt.Errorf("section [%s] has unknown type: %s", line, matches[0][2])
@@ -66,8 +129,11 @@ func (inventory *InventoryData) parse(reader *bufio.Reader) error {
 		}
 
 		if activeState == hostsState {
-			hosts, err := inventory.getHosts(line, activeGroup)
+			hosts, err := inventory.getHosts(ctx, line, lineNumber, activeGroup, options.expansionLimit())
 			if err != nil {
+				if errors.Is(err, ErrLimitExceeded) {
+					return fmt.Errorf("line %d: %w", lineNumber, err)
+				}
 				return err
 			}
 			for _, host := range hosts {
@@ -77,6 +143,9 @@ func (inventory *InventoryData) parse(reader *bufio.Reader) error {
 					delete(host.DirectGroups, "ungrouped")
 				}
 			}
+			if options.MaxHosts > 0 && len(inventory.Hosts) > options.MaxHosts {
+				return fmt.Errorf("line %d: more than %d hosts in inventory: %w", lineNumber, options.MaxHosts, ErrLimitExceeded)
+			}
 		}
 		if activeState == childrenState {
 			parsed, err := shlex.Split(line)
@@ -85,6 +154,7 @@ func (inventory *InventoryData) parse(reader *bufio.Reader) error {
 			}
 			groupName := parsed[0]
 			newGroup := inventory.getOrCreateGroup(groupName)
+			inventory.recordGroupLine(newGroup.Name, lineNumber)
 			newGroup.DirectParents[activeGroup.Name] = activeGroup
 			inventory.Groups[line] = newGroup
 		}
@@ -96,21 +166,27 @@ func (inventory *InventoryData) parse(reader *bufio.Reader) error {
 			activeGroup.InventoryVars[k] = v
 		}
 	}
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return fmt.Errorf("line %d: longer than %d bytes: %w", lineNumber+1, maxLineLength, ErrLimitExceeded)
+		}
+		return err
+	}
 	inventory.Groups[activeGroup.Name] = activeGroup
 	return nil
 }
 
-// getHosts parses given "host" line from inventory
-func (inventory *InventoryData) getHosts(line string, group *Group) (map[string]*Host, error) {
+// getHosts parses given "host" line from inventory, expanding into no more than `limit` hosts if limit is positive
+func (inventory *InventoryData) getHosts(ctx context.Context, line string, lineNumber int, group *Group, limit int) (map[string]*Host, error) {
 	parts, err := shlex.Split(line)
 	if err != nil {
 		return nil, err
 	}
-	hostpattern, port, err := getHostPort(parts[0])
+	hostpattern, port, portSet, err := getHostPort(parts[0])
 	if err != nil {
 		return nil, err
 	}
-	hostnames, err := expandHostPattern(hostpattern)
+	hostnames, err := expandHostPatternContext(ctx, hostpattern, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -128,14 +204,23 @@ func (inventory *InventoryData) getHosts(line string, group *Group) (map[string]
 
 		host := inventory.getOrCreateHost(hostname)
 		host.Port = port
+		host.portSet = portSet
 		host.DirectGroups[group.Name] = group
 		addValues(host.InventoryVars, vars)
+		inventory.hostLines[host.Name] = append(inventory.hostLines[host.Name], hostDefinition{line: lineNumber, vars: vars})
 
 		result[host.Name] = host
 	}
 	return result, nil
 }
 
+// recordGroupLine remembers the first line where the group appears
+func (inventory *InventoryData) recordGroupLine(name string, lineNumber int) {
+	if _, ok := inventory.groupLines[name]; !ok {
+		inventory.groupLines[name] = lineNumber
+	}
+}
+
 // splitKV splits `key=value` into two string: key and value
 func splitKV(kv string) (string, string, error) {
 	keyval := strings.SplitN(kv, "=", 2)
@@ -146,23 +231,29 @@ func splitKV(kv string) (string, string, error) {
 }
 
 // getHostPort splits string like `host-[a:b]-c:22` into `host-[a:b]-c` and `22`
-func getHostPort(str string) (string, int, error) {
-	port := 22
+// The returned bool tells whether the port was actually specified
+func getHostPort(str string) (string, int, bool, error) {
+	port := defaultPort
 	parts := strings.Split(str, ":")
 	if len(parts) == 1 {
-		return str, port, nil
+		return str, port, false, nil
 	}
 	lastPart := parts[len(parts)-1]
 	if strings.Contains(lastPart, "]") {
 		// We are in expand pattern, so no port were specified
-		return str, port, nil
+		return str, port, false, nil
 	}
 	port, err := strconv.Atoi(lastPart)
-	return strings.Join(parts[:len(parts)-1], ":"), port, err
+	return strings.Join(parts[:len(parts)-1], ":"), port, true, err
 }
 
 // expandHostPattern turns `host-[a:b]-c` into a flat list of hosts
 func expandHostPattern(hostpattern string) ([]string, error) {
+	return expandHostPatternContext(context.Background(), hostpattern, 0)
+}
+
+// expandHostPatternContext turns `host-[a:b]-c` into a flat list of no more than `limit` hosts if limit is positive
+func expandHostPatternContext(ctx context.Context, hostpattern string, limit int) ([]string, error) {
 	lbrac := strings.Replace(hostpattern, "[", "|", 1)
 	rbrac := strings.Replace(lbrac, "]", "|", 1)
 	parts := strings.Split(rbrac, "|")
@@ -184,7 +275,10 @@ func expandHostPattern(hostpattern string) ([]string, error) {
 	var begin, end []rune
 	var step = 1
 	if len(bounds) == 3 {
-		step, _ = strconv.Atoi(bounds[2])
+		var err error
+		if step, err = strconv.Atoi(bounds[2]); err != nil || step <= 0 {
+			return nil, fmt.Errorf("bad range step specified: %s", nrange)
+		}
 	}
 
 	end = []rune(bounds[1])
@@ -203,7 +297,11 @@ func expandHostPattern(hostpattern string) ([]string, error) {
 	isNumberRange := false
 
 	if isRunesNumber(begin) && isRunesNumber(end) {
-		chars = makeRange(runesToInt(begin), runesToInt(end), step)
+		first, last := runesToInt(begin), runesToInt(end)
+		if limit > 0 && last >= first && (last-first)/step+1 > limit {
+			return nil, fmt.Errorf("host pattern %s expands into more than %d hosts: %w", hostpattern, limit, ErrLimitExceeded)
+		}
+		chars = makeRange(first, last, step)
 		isNumberRange = true
 	} else if !isRunesNumber(begin) && !isRunesNumber(end) && len(begin) == 1 && len(end) == 1 {
 		dict := append(makeRange('a', 'z', 1), makeRange('A', 'Z', 1)...)
@@ -229,13 +327,28 @@ func expandHostPattern(hostpattern string) ([]string, error) {
 		format = "%s%c%s"
 	}
 
-	for _, c := range chars {
+	for i, c := range chars {
+		if i%4096 == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
 		hosts = append(hosts, fmt.Sprintf(format, head, c, tail))
 	}
 
 	var result []string
-	for _, hostpattern := range hosts {
-		newHosts, err := expandHostPattern(hostpattern)
+	for _, subpattern := range hosts {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		remaining := 0
+		if limit > 0 {
+			remaining = limit - len(result)
+			if remaining <= 0 {
+				return nil, fmt.Errorf("host pattern %s expands into more than %d hosts: %w", hostpattern, limit, ErrLimitExceeded)
+			}
+		}
+		newHosts, err := expandHostPatternContext(ctx, subpattern, remaining)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,254 @@
+package aini
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"golang.org/x/exp/maps"
+)
+
+// patternTermKind is the role of a sub-pattern in Pattern
+type patternTermKind int
+
+const (
+	includeTerm   patternTermKind = iota // `name`: union
+	excludeTerm                          // `!name`: exclusion
+	intersectTerm                        // `&name`: intersection
+)
+
+// patternTerm is a sub-pattern of Pattern
+type patternTerm struct {
+	source string
+	kind   patternTermKind
+	// glob matches names of hosts and groups, empty for `all` and `*` which match any host
+	glob      string
+	lowerGlob string
+}
+
+// Pattern is a compiled list of Ansible host patterns as described in https://docs.ansible.com/ansible/latest/inventory_guide/intro_patterns.html,
+// which can be reused across inventories and hosts.
+//
+// e.g. "webservers:gateways:myhost.domain:!atlanta"
+type Pattern struct {
+	source string
+	terms  []patternTerm
+	// matchesNothing is set when the first sub-pattern is empty, as Ansible matches no hosts then
+	matchesNothing bool
+}
+
+// CompilePattern compiles Ansible host patterns separated by colons, checking syntax of all sub-patterns
+func CompilePattern(patterns string) (*Pattern, error) {
+	return compilePatternList(patterns, strings.Split(patterns, ":"))
+}
+
+func compilePatternList(source string, patterns []string) (*Pattern, error) {
+	compiled := &Pattern{source: source, terms: make([]patternTerm, 0, len(patterns))}
+	for index, pattern := range patterns {
+		if pattern == "" {
+			if index == 0 {
+				compiled.matchesNothing = true
+			}
+			continue
+		}
+		term := patternTerm{source: pattern, kind: includeTerm, glob: pattern}
+		switch pattern[0] {
+		case '!':
+			if index == 0 {
+				return nil, fmt.Errorf("exclusion pattern \"%s\" cannot be the first pattern", pattern)
+			}
+			term.kind, term.glob = excludeTerm, pattern[1:]
+		case '&':
+			if index == 0 {
+				return nil, fmt.Errorf("intersection pattern \"%s\" cannot be the first pattern", pattern)
+			}
+			term.kind, term.glob = intersectTerm, pattern[1:]
+		default:
+			if pattern == "all" || pattern == "*" {
+				term.glob = ""
+			}
+		}
+		if _, err := path.Match(term.glob, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern \"%s\": %w", pattern, err)
+		}
+		term.lowerGlob = strings.ToLower(term.glob)
+		compiled.terms = append(compiled.terms, term)
+	}
+	return compiled, nil
+}
+
+func (pattern *Pattern) String() string {
+	return pattern.source
+}
+
+// matchName returns the first of names matching the sub-pattern and whether there is any
+func (term *patternTerm) matchName(names []string, caseInsensitive bool) (string, bool) {
+	if term.kind == includeTerm && term.glob == "" {
+		return "all", true
+	}
+	glob := term.glob
+	if caseInsensitive {
+		glob = term.lowerGlob
+	}
+	for _, name := range names {
+		candidate := name
+		if caseInsensitive {
+			candidate = strings.ToLower(name)
+		}
+		// syntax is checked by CompilePattern
+		if matched, _ := path.Match(glob, candidate); matched {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// MatchNames checks whether the given hostname and group names match the patterns
+func (pattern *Pattern) MatchNames(names []string) bool {
+	return pattern.matchNames(names, false)
+}
+
+func (pattern *Pattern) matchNames(names []string, caseInsensitive bool) bool {
+	if pattern.matchesNothing {
+		return false
+	}
+	numPositiveMatch := 0
+	for index := range pattern.terms {
+		term := &pattern.terms[index]
+		_, matched := term.matchName(names, caseInsensitive)
+		switch {
+		case term.kind == excludeTerm && matched:
+			return false
+		case term.kind == intersectTerm && !matched:
+			return false
+		case term.kind == includeTerm && matched:
+			numPositiveMatch++
+		}
+	}
+	return numPositiveMatch > 0
+}
+
+// hostAndGroupNames returns the host name followed by names of all its groups
+func hostAndGroupNames(host *Host) []string {
+	names := make([]string, 0, 1+len(host.Groups))
+	names = append(names, host.Name)
+	return append(names, maps.Keys(host.Groups)...)
+}
+
+// MatchHost checks whether the host or any of its groups match the patterns
+func (pattern *Pattern) MatchHost(host *Host) bool {
+	return pattern.MatchNames(hostAndGroupNames(host))
+}
+
+// MatchHosts looks for all hosts of the inventory matching the patterns, case-insensitively if set by SetCaseInsensitive
+func (pattern *Pattern) MatchHosts(inventory *InventoryData) map[string]*Host {
+	matchedHosts := make(map[string]*Host)
+	for _, host := range inventory.Hosts {
+		if pattern.matchNames(hostAndGroupNames(host), inventory.caseInsensitive) {
+			matchedHosts[host.Name] = host
+		}
+	}
+	return matchedHosts
+}
+
+// PatternStep describes how a sub-pattern affected the match of a host
+type PatternStep struct {
+	// Pattern is the sub-pattern as written, e.g. "!atlanta"
+	Pattern string
+	// MatchedName is the name of the host or its group matched by the sub-pattern, empty if none
+	MatchedName string
+	// Effect is one of "included", "not included", "excluded", "not excluded", "kept" and "dropped"
+	Effect string
+}
+
+// PatternExplanation describes why a host matches patterns or not
+type PatternExplanation struct {
+	Host    string
+	Matched bool
+	Steps   []PatternStep
+	// Reason is a summary of the decision
+	Reason string
+}
+
+func (explanation PatternExplanation) String() string {
+	var sb strings.Builder
+	if explanation.Matched {
+		fmt.Fprintf(&sb, "%s matched: %s", explanation.Host, explanation.Reason)
+	} else {
+		fmt.Fprintf(&sb, "%s not matched: %s", explanation.Host, explanation.Reason)
+	}
+	for _, step := range explanation.Steps {
+		if step.MatchedName != "" {
+			fmt.Fprintf(&sb, "\n  %s: %s by %s", step.Pattern, step.Effect, step.MatchedName)
+		} else {
+			fmt.Fprintf(&sb, "\n  %s: %s", step.Pattern, step.Effect)
+		}
+	}
+	return sb.String()
+}
+
+// Explain describes which sub-patterns included or excluded the host, comparing names case-sensitively as MatchHost
+func (pattern *Pattern) Explain(host *Host) PatternExplanation {
+	return pattern.explain(host, false)
+}
+
+// ExplainIn describes which sub-patterns included or excluded the host of the inventory,
+// comparing names case-insensitively if set by SetCaseInsensitive as MatchHosts
+func (pattern *Pattern) ExplainIn(inventory *InventoryData, host *Host) PatternExplanation {
+	return pattern.explain(host, inventory.caseInsensitive)
+}
+
+func (pattern *Pattern) explain(host *Host, caseInsensitive bool) PatternExplanation {
+	explanation := PatternExplanation{Host: host.Name, Steps: make([]PatternStep, 0, len(pattern.terms))}
+	if pattern.matchesNothing {
+		explanation.Reason = "the first pattern is empty, which matches no hosts"
+		return explanation
+	}
+
+	names := append([]string{host.Name}, sortedKeys(host.Groups)...)
+	included, excluded, dropped := -1, -1, -1
+	for index := range pattern.terms {
+		term := &pattern.terms[index]
+		name, matched := term.matchName(names, caseInsensitive)
+		step := PatternStep{Pattern: term.source, MatchedName: name}
+		switch term.kind {
+		case includeTerm:
+			step.Effect = stepEffect(matched, "included", "not included")
+			if matched && included < 0 {
+				included = index
+			}
+		case excludeTerm:
+			step.Effect = stepEffect(matched, "excluded", "not excluded")
+			if matched && excluded < 0 {
+				excluded = index
+			}
+		case intersectTerm:
+			step.Effect = stepEffect(matched, "kept", "dropped")
+			if !matched && dropped < 0 {
+				dropped = index
+			}
+		}
+		explanation.Steps = append(explanation.Steps, step)
+	}
+
+	steps := explanation.Steps
+	switch {
+	case excluded >= 0:
+		explanation.Reason = fmt.Sprintf("excluded by %s matching %s", steps[excluded].Pattern, steps[excluded].MatchedName)
+	case dropped >= 0:
+		explanation.Reason = fmt.Sprintf("dropped by %s matching neither the host nor its groups", steps[dropped].Pattern)
+	case included < 0:
+		explanation.Reason = "no pattern includes the host or its groups"
+	default:
+		explanation.Matched = true
+		explanation.Reason = fmt.Sprintf("included by %s matching %s", steps[included].Pattern, steps[included].MatchedName)
+	}
+	return explanation
+}
+
+func stepEffect(matched bool, ifMatched string, otherwise string) string {
+	if matched {
+		return ifMatched
+	}
+	return otherwise
+}
@@ -0,0 +1,103 @@
+package aini
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/exp/maps"
+)
+
+func TestCompilePattern(t *testing.T) {
+	for _, patterns := range []string{"!web", "&web", "web:[", "web:!["} {
+		_, err := CompilePattern(patterns)
+		assert.NotNil(t, err, patterns)
+	}
+
+	pattern, err := CompilePattern("web:db*:!host3:&all")
+	assert.Nil(t, err)
+	assert.Equal(t, "web:db*:!host3:&all", pattern.String())
+
+	first := parseString(t, `
+	[web]
+	host1
+	host3
+	[db1]
+	host2
+	`)
+	second := parseString(t, `
+	[web]
+	host3
+	host4
+	`)
+	assert.ElementsMatch(t, []string{"host1", "host2"}, maps.Keys(pattern.MatchHosts(first)))
+	assert.ElementsMatch(t, []string{"host4"}, maps.Keys(pattern.MatchHosts(second)))
+	assert.True(t, pattern.MatchHost(first.Hosts["host1"]))
+	assert.False(t, pattern.MatchHost(first.Hosts["host3"]))
+
+	pattern, err = CompilePattern(":web")
+	assert.Nil(t, err)
+	assert.Empty(t, pattern.MatchHosts(first))
+
+	// errors are raised up front even if no hosts would reach the sub-pattern
+	_, err = parseString(t, "").MatchHostsByPatterns("web:[")
+	assert.NotNil(t, err)
+}
+
+func TestPatternCaseInsensitive(t *testing.T) {
+	v := parseString(t, `
+	[Web]
+	Host1
+	`)
+	pattern, err := CompilePattern("WEB:&host*")
+	assert.Nil(t, err)
+	assert.Empty(t, pattern.MatchHosts(v))
+	assert.Nil(t, v.SetCaseInsensitive())
+	assert.ElementsMatch(t, []string{"Host1"}, maps.Keys(pattern.MatchHosts(v)))
+
+	assert.False(t, pattern.Explain(v.Hosts["Host1"]).Matched)
+	explanation := pattern.ExplainIn(v, v.Hosts["Host1"])
+	assert.True(t, explanation.Matched)
+	assert.Equal(t, []PatternStep{
+		{Pattern: "WEB", MatchedName: "Web", Effect: "included"},
+		{Pattern: "&host*", MatchedName: "Host1", Effect: "kept"},
+	}, explanation.Steps)
+}
+
+func TestPatternExplain(t *testing.T) {
+	v := parseString(t, `
+	[web]
+	host1
+	host2
+	[atlanta]
+	host2
+	[eu]
+	host1
+	host3
+	`)
+	pattern, err := CompilePattern("web:!atlanta:&eu")
+	assert.Nil(t, err)
+
+	explanation := pattern.Explain(v.Hosts["host1"])
+	assert.True(t, explanation.Matched)
+	assert.Equal(t, []PatternStep{
+		{Pattern: "web", MatchedName: "web", Effect: "included"},
+		{Pattern: "!atlanta", Effect: "not excluded"},
+		{Pattern: "&eu", MatchedName: "eu", Effect: "kept"},
+	}, explanation.Steps)
+	assert.Equal(t, "host1 matched: included by web matching web\n  web: included by web\n  !atlanta: not excluded\n  &eu: kept by eu", explanation.String())
+
+	explanation = pattern.Explain(v.Hosts["host2"])
+	assert.False(t, explanation.Matched)
+	assert.Equal(t, "excluded by !atlanta matching atlanta", explanation.Reason)
+
+	explanation = pattern.Explain(v.Hosts["host3"])
+	assert.False(t, explanation.Matched)
+	assert.Equal(t, "no pattern includes the host or its groups", explanation.Reason)
+
+	pattern, _ = CompilePattern("all:&web")
+	explanation = pattern.Explain(v.Hosts["host3"])
+	assert.Equal(t, "dropped by &web matching neither the host nor its groups", explanation.Reason)
+
+	pattern, _ = CompilePattern(":host3")
+	assert.False(t, pattern.Explain(v.Hosts["host3"]).Matched)
+}
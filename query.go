@@ -0,0 +1,91 @@
+package aini
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Query is a compiled expression selecting hosts by their resolved variables and groups, e.g.
+//
+//	env == "prod" && "web" in groups && ansible_port != 22 && kernel_version < 5.10 && role matches "web*"
+//
+// The syntax is the subset of Jinja2 expressions supported by constructed inventory (see ConstructedConfig).
+// Besides host variables, expressions can refer to:
+//
+//   - inventory_hostname, inventory_hostname_short: the host name
+//   - group_names: names of groups of the host, except all and ungrouped, as in Ansible
+//   - groups: names of all groups of the host including all and ungrouped. Unlike Ansible it's not a dict of all groups.
+//
+// Hosts for which the expression refers to undefined variables don't match, unless tested by `is defined` or `default`,
+// and neither do hosts whose values cannot be compared, e.g. a list to a number.
+// Dotted versions and number literals like 5.10 are compared by components, so `kernel_version < 5.10` holds for "5.9".
+// Besides regular expressions by `is match(...)`, `role matches "web*"` matches values by shell globs.
+type Query struct {
+	expr *expression
+}
+
+// CompileQuery compiles query expression
+func CompileQuery(source string) (*Query, error) {
+	expr, err := compileExpression(source)
+	if err != nil {
+		return nil, err
+	}
+	return &Query{expr: expr}, nil
+}
+
+func (query *Query) String() string {
+	return query.expr.source
+}
+
+// MatchHost checks whether the host matches the query.
+// The error is returned for invalid operations other than use of undefined variables or comparison of different types,
+// e.g. an invalid regular expression.
+func (query *Query) MatchHost(host *Host) (bool, error) {
+	matched, err := query.expr.evalBool(host.queryEnv())
+	if err != nil {
+		var undefinedErr *undefinedVarError
+		var incomparableErr *incomparableError
+		if errors.As(err, &undefinedErr) || errors.As(err, &incomparableErr) {
+			return false, nil
+		}
+		return false, fmt.Errorf("host %s: %w", host.Name, err)
+	}
+	return matched, nil
+}
+
+// queryEnv provides variables and group names of the host to query expressions
+func (host *Host) queryEnv() exprEnv {
+	groups := make([]any, 0, len(host.Groups))
+	for _, group := range GroupMapListValues(host.Groups) {
+		groups = append(groups, group.Name)
+	}
+	return chainEnv{mapEnv{"groups": groups}, host.magicExprEnv(), varsEnv(host.Vars)}
+}
+
+// QueryHosts looks for all hosts matching the query
+func (inventory *InventoryData) QueryHosts(query *Query) (map[string]*Host, error) {
+	return queryHosts(inventory.Hosts, query)
+}
+
+// MatchHostsByPatternsAndQuery looks for hosts matching both the Ansible host patterns and the query
+func (inventory *InventoryData) MatchHostsByPatternsAndQuery(patterns string, query *Query) (map[string]*Host, error) {
+	hosts, err := inventory.MatchHostsByPatterns(patterns)
+	if err != nil {
+		return nil, err
+	}
+	return queryHosts(hosts, query)
+}
+
+func queryHosts(hosts map[string]*Host, query *Query) (map[string]*Host, error) {
+	matchedHosts := make(map[string]*Host)
+	for name, host := range hosts {
+		matched, err := query.MatchHost(host)
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			matchedHosts[name] = host
+		}
+	}
+	return matchedHosts, nil
+}
@@ -0,0 +1,70 @@
+package aini
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/exp/maps"
+)
+
+func TestQueryHosts(t *testing.T) {
+	v := parseString(t, `
+	web1 env=prod kernel_version=5.4.0 ansible_port=2222 role=webserver
+	web2 env=prod kernel_version=5.15.0 ansible_port=2222 role=webserver
+	web3 env=staging kernel_version=5.4.0 role=webserver
+	db1 env=prod kernel_version=5.4.0 role=database
+	[web]
+	web1
+	web2
+	web3
+	[db]
+	db1
+	`)
+
+	cases := map[string][]string{
+		`env == "prod" && "web" in groups && ansible_port != 22`:                            {"web1", "web2"},
+		`env == "prod" and kernel_version is version("5.10", "<") and role is match("web")`: {"web1"},
+		`"ungrouped" in groups`:                        {},
+		`"all" in groups and "all" not in group_names`: {"db1", "web1", "web2", "web3"},
+		`ansible_port == 2222`:                         {"web1", "web2"},
+		`ansible_port is not defined`:                  {"db1", "web3"},
+		`inventory_hostname_short ~ "" == "db1"`:       {"db1"},
+		`kernel_version < 5.10`:                        {"db1", "web1", "web3"},
+		`kernel_version >= "5.10"`:                     {"web2"},
+		`role matches "web*" and env != "staging"`:     {"web1", "web2"},
+		`role not matches "web*"`:                      {"db1"},
+		`groups < 1`:                                   {},
+	}
+	for source, expected := range cases {
+		query, err := CompileQuery(source)
+		assert.Nil(t, err, source)
+		assert.Equal(t, source, query.String())
+		hosts, err := v.QueryHosts(query)
+		assert.Nil(t, err, source)
+		assert.ElementsMatch(t, expected, maps.Keys(hosts), source)
+	}
+
+	query, err := CompileQuery(`env == "prod"`)
+	assert.Nil(t, err)
+	hosts, err := v.MatchHostsByPatternsAndQuery("web:!web2", query)
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"web1"}, maps.Keys(hosts))
+
+	_, err = CompileQuery(`env ==`)
+	assert.NotNil(t, err)
+
+	query, err = CompileQuery(`role matches "[web"`)
+	assert.Nil(t, err)
+	_, err = v.QueryHosts(query)
+	assert.NotNil(t, err)
+}
+
+func TestLoadQueryHosts(t *testing.T) {
+	v, err := Load("test_data/inventory")
+	assert.Nil(t, err)
+	query, err := CompileQuery(`nginx_bool_var and web_int_var == 1`)
+	assert.Nil(t, err)
+	hosts, err := v.QueryHosts(query)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"host1", "host3", "host4"}, hosts)
+}
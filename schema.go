@@ -0,0 +1,288 @@
+package aini
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// VarSchema defines conventions of resolved host and group variables, e.g.
+//
+//	rules:
+//	  - hosts: all
+//	    vars:
+//	      env: {required: true, enum: [prod, staging]}
+//	      ansible_port: {type: int, min: 1, max: 65535}
+//	  - hosts: db
+//	    vars:
+//	      db_role: {required: true}
+//	  - groups: "*_servers"
+//	    vars:
+//	      owner: {required: true, pattern: "^team-"}
+type VarSchema struct {
+	Rules []VarSchemaRule `yaml:"rules" json:"rules"`
+}
+
+// VarSchemaRule applies specifications of variables to hosts or groups
+type VarSchemaRule struct {
+	// Hosts are Ansible host patterns as in MatchHostsByPatterns, selecting hosts whose Vars are validated
+	Hosts string `yaml:"hosts" json:"hosts,omitempty"`
+	// Groups is a pattern of group names as in MatchGroups, selecting groups whose Vars are validated
+	Groups string `yaml:"groups" json:"groups,omitempty"`
+	// Vars are specifications by variable names
+	Vars map[string]VarSpec `yaml:"vars" json:"vars"`
+}
+
+// VarSpec is the specification of a variable. Zero values mean no restriction.
+type VarSpec struct {
+	Required bool `yaml:"required" json:"required,omitempty"`
+	// Type is one of string, int, float, bool, list and object. Lists and objects are stored in JSON.
+	Type string `yaml:"type" json:"type,omitempty"`
+	// Enum lists allowed values
+	Enum []string `yaml:"enum" json:"enum,omitempty"`
+	// Pattern is a regular expression the value must match
+	Pattern string `yaml:"pattern" json:"pattern,omitempty"`
+	// Min and Max limit numeric values, or lengths of other types
+	Min *float64 `yaml:"min" json:"min,omitempty"`
+	Max *float64 `yaml:"max" json:"max,omitempty"`
+}
+
+// VarViolation is a variable of a host or group not conforming to VarSchema
+type VarViolation struct {
+	// Host or Group is the name of the host or group whose variable is invalid
+	Host  string `json:"host,omitempty"`
+	Group string `json:"group,omitempty"`
+	Var   string `json:"var"`
+	Value string `json:"value,omitempty"`
+	// Source tells where the offending value is defined, nil for missing variables
+	Source  *VarSource `json:"source,omitempty"`
+	Message string     `json:"message"`
+}
+
+func (violation VarViolation) String() string {
+	subject := "host " + violation.Host
+	if violation.Host == "" {
+		subject = "group " + violation.Group
+	}
+	if violation.Source == nil {
+		return fmt.Sprintf("%s: %s: %s", subject, violation.Var, violation.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s (from %s)", subject, violation.Var, violation.Message, violation.Source)
+}
+
+// LoadVarSchema loads VarSchema from a YAML or JSON file
+func LoadVarSchema(path string) (*VarSchema, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseVarSchema(f)
+}
+
+// ParseVarSchema parses VarSchema in YAML or JSON, checking its patterns and types
+func ParseVarSchema(r io.Reader) (*VarSchema, error) {
+	schema := &VarSchema{}
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(schema); err != nil && err != io.EOF {
+		return nil, err
+	}
+	if _, err := schema.compile(); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+var varSpecTypes = map[string]bool{"": true, "string": true, "int": true, "float": true, "bool": true, "list": true, "object": true}
+
+// compiledVarSchema contains regular expressions of VarSchema by rule index and variable name
+type compiledVarSchema struct {
+	patterns []map[string]*regexp.Regexp
+}
+
+func (schema *VarSchema) compile() (*compiledVarSchema, error) {
+	compiled := &compiledVarSchema{}
+	for index, rule := range schema.Rules {
+		if (rule.Hosts == "") == (rule.Groups == "") {
+			return nil, fmt.Errorf("rules[%d]: exactly one of hosts and groups must be set", index)
+		}
+		if rule.Hosts != "" {
+			if _, err := CompilePattern(rule.Hosts); err != nil {
+				return nil, fmt.Errorf("rules[%d]: hosts: %w", index, err)
+			}
+		}
+		if rule.Groups != "" {
+			if _, err := path.Match(rule.Groups, ""); err != nil {
+				return nil, fmt.Errorf("rules[%d]: groups: %w", index, err)
+			}
+		}
+		patterns := make(map[string]*regexp.Regexp)
+		for name, spec := range rule.Vars {
+			if !varSpecTypes[spec.Type] {
+				return nil, fmt.Errorf("rules[%d]: %s: unknown type %s", index, name, spec.Type)
+			}
+			if spec.Pattern != "" {
+				re, err := regexp.Compile(spec.Pattern)
+				if err != nil {
+					return nil, fmt.Errorf("rules[%d]: %s: %w", index, name, err)
+				}
+				patterns[name] = re
+			}
+		}
+		compiled.patterns = append(compiled.patterns, patterns)
+	}
+	return compiled, nil
+}
+
+// ValidateVars checks resolved Vars of hosts and groups against the schema, returning all violations
+// of hosts and then groups, ordered by names and variable names.
+// The error is only returned for invalid schema or host patterns.
+func (inventory *InventoryData) ValidateVars(schema *VarSchema) ([]VarViolation, error) {
+	compiled, err := schema.compile()
+	if err != nil {
+		return nil, err
+	}
+	violations := make([]VarViolation, 0)
+	for index, rule := range schema.Rules {
+		names := sortedKeys(rule.Vars)
+		if rule.Hosts != "" {
+			hosts, err := inventory.MatchHostsByPatterns(rule.Hosts)
+			if err != nil {
+				return nil, fmt.Errorf("rules[%d]: hosts: %w", index, err)
+			}
+			for _, host := range HostMapListValues(hosts) {
+				for _, name := range names {
+					value, found := host.Vars[name]
+					for _, message := range rule.Vars[name].check(value, found, compiled.patterns[index][name]) {
+						violation := VarViolation{Host: host.Name, Var: name, Value: value, Message: message}
+						if source, ok := host.VarSource(name); ok {
+							violation.Source = &source
+						}
+						violations = append(violations, violation)
+					}
+				}
+			}
+			continue
+		}
+		groups, err := inventory.MatchGroups(rule.Groups)
+		if err != nil {
+			return nil, fmt.Errorf("rules[%d]: groups: %w", index, err)
+		}
+		for _, group := range GroupMapListValues(groups) {
+			for _, name := range names {
+				value, found := group.Vars[name]
+				for _, message := range rule.Vars[name].check(value, found, compiled.patterns[index][name]) {
+					violation := VarViolation{Group: group.Name, Var: name, Value: value, Message: message}
+					if source, ok := group.VarSource(name); ok {
+						violation.Source = &source
+					}
+					violations = append(violations, violation)
+				}
+			}
+		}
+	}
+	sort.SliceStable(violations, func(i, j int) bool {
+		a, b := violations[i], violations[j]
+		if (a.Host == "") != (b.Host == "") {
+			return a.Host != ""
+		}
+		if a.Host != b.Host {
+			return a.Host < b.Host
+		}
+		if a.Group != b.Group {
+			return a.Group < b.Group
+		}
+		return a.Var < b.Var
+	})
+	return violations, nil
+}
+
+// check returns messages of all violations of the spec by the value
+func (spec VarSpec) check(value string, found bool, pattern *regexp.Regexp) []string {
+	if !found {
+		if spec.Required {
+			return []string{"required variable is missing"}
+		}
+		return nil
+	}
+
+	messages := make([]string, 0)
+	size, typeErr := spec.checkType(value)
+	if typeErr != nil {
+		messages = append(messages, typeErr.Error())
+	}
+	if len(spec.Enum) > 0 {
+		allowed := false
+		for _, option := range spec.Enum {
+			if value == option {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			messages = append(messages, fmt.Sprintf("value '%s' is not one of %s", value, strings.Join(spec.Enum, ", ")))
+		}
+	}
+	if pattern != nil && !pattern.MatchString(value) {
+		messages = append(messages, fmt.Sprintf("value '%s' does not match pattern %s", value, spec.Pattern))
+	}
+	if typeErr == nil {
+		if spec.Min != nil && size < *spec.Min {
+			messages = append(messages, fmt.Sprintf("value '%s' is less than %s", value, formatNumber(*spec.Min)))
+		}
+		if spec.Max != nil && size > *spec.Max {
+			messages = append(messages, fmt.Sprintf("value '%s' is greater than %s", value, formatNumber(*spec.Max)))
+		}
+	}
+	return messages
+}
+
+// checkType checks the value is of the type, returning the number to compare with Min and Max:
+// the value of numbers, or the length of strings, lists and objects
+func (spec VarSpec) checkType(value string) (float64, error) {
+	switch spec.Type {
+	case "int":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, fmt.Errorf("value '%s' is not an int", value)
+		}
+		return float64(n), nil
+	case "float":
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return 0, fmt.Errorf("value '%s' is not a float", value)
+		}
+		return n, nil
+	case "bool":
+		if _, err := parseBool(value); err != nil || value == "" {
+			return 0, fmt.Errorf("value '%s' is not a bool", value)
+		}
+		return 0, nil
+	case "list":
+		var list []any
+		if err := json.Unmarshal([]byte(value), &list); err != nil || list == nil {
+			return 0, fmt.Errorf("value '%s' is not a list", value)
+		}
+		return float64(len(list)), nil
+	case "object":
+		var object map[string]any
+		if err := json.Unmarshal([]byte(value), &object); err != nil || object == nil {
+			return 0, fmt.Errorf("value '%s' is not an object", value)
+		}
+		return float64(len(object)), nil
+	}
+	return float64(len(value)), nil
+}
+
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
@@ -0,0 +1,106 @@
+package aini
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testVarSchema = `
+rules:
+  - hosts: all
+    vars:
+      env:
+        required: true
+        enum: [prod, staging]
+      ansible_port: {type: int, min: 1, max: 65535}
+  - hosts: db
+    vars:
+      db_role: {required: true, pattern: "^(primary|replica)$"}
+      tags: {type: list, max: 2}
+  - groups: "d*"
+    vars:
+      owner: {required: true}
+`
+
+func TestValidateVars(t *testing.T) {
+	schema, err := ParseVarSchema(strings.NewReader(testVarSchema))
+	assert.Nil(t, err)
+
+	v := parseString(t, `
+	web1 env=prod ansible_port=2222
+	[db]
+	db1 db_role=primary tags='["a","b","c"]'
+	db2 db_role=leader ansible_port=70000
+	[db:vars]
+	env=dev
+	owner=dba
+	[dmz]
+	dmz1 env=staging ansible_port=22x
+	`)
+	violations, err := v.ValidateVars(schema)
+	assert.Nil(t, err)
+
+	messages := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		messages = append(messages, violation.String())
+	}
+	assert.Equal(t, []string{
+		"host db1: env: value 'dev' is not one of prod, staging (from inventory vars of group db)",
+		"host db1: tags: value '[\"a\",\"b\",\"c\"]' is greater than 2 (from inventory line of host db1)",
+		"host db2: ansible_port: value '70000' is greater than 65535 (from inventory line of host db2)",
+		"host db2: db_role: value 'leader' does not match pattern ^(primary|replica)$ (from inventory line of host db2)",
+		"host db2: env: value 'dev' is not one of prod, staging (from inventory vars of group db)",
+		"host dmz1: ansible_port: value '22x' is not an int (from inventory line of host dmz1)",
+		"group dmz: owner: required variable is missing",
+	}, messages)
+	assert.Equal(t, VarViolation{
+		Host:    "db1",
+		Var:     "env",
+		Value:   "dev",
+		Source:  &VarSource{Kind: VarSourceGroupInventory, Name: "db"},
+		Message: "value 'dev' is not one of prod, staging",
+	}, violations[0])
+}
+
+func TestVarSpecTypes(t *testing.T) {
+	cases := []struct {
+		spec  VarSpec
+		value string
+		valid bool
+	}{
+		{VarSpec{Type: "bool"}, "yes", true},
+		{VarSpec{Type: "bool"}, "maybe", false},
+		{VarSpec{Type: "float"}, "1.5", true},
+		{VarSpec{Type: "float"}, "x", false},
+		{VarSpec{Type: "object"}, `{"a":1}`, true},
+		{VarSpec{Type: "object"}, `[1]`, false},
+		{VarSpec{Type: "list"}, `{"a":1}`, false},
+		{VarSpec{Type: "string"}, `anything`, true},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.valid, len(c.spec.check(c.value, true, nil)) == 0, "%s %s", c.spec.Type, c.value)
+	}
+	min := 3.0
+	assert.Len(t, VarSpec{Min: &min}.check("ab", true, nil), 1)
+	assert.Empty(t, VarSpec{}.check("", false, nil))
+}
+
+func TestParseVarSchemaErrors(t *testing.T) {
+	for _, input := range []string{
+		"rules:\n  - vars: {a: {}}\n",
+		"rules:\n  - hosts: all\n    groups: all\n",
+		"rules:\n  - hosts: all\n    vars: {a: {type: number}}\n",
+		"rules:\n  - hosts: all\n    vars: {a: {pattern: '('}}\n",
+		"rules:\n  - groups: '['\n",
+		"rules:\n  - hosts: all\n    unknown: 1\n",
+	} {
+		_, err := ParseVarSchema(strings.NewReader(input))
+		assert.NotNil(t, err, input)
+	}
+
+	schema := &VarSchema{Rules: []VarSchemaRule{{Hosts: "!web"}}}
+	_, err := parseString(t, "host1\n").ValidateVars(schema)
+	assert.NotNil(t, err)
+}
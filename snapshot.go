@@ -0,0 +1,52 @@
+package aini
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// Snapshot returns a read-only Inventory of a deep copy of the inventory, which should be reconciled.
+// The Inventory is safe for concurrent use without locks, as nothing changes it,
+// and is not affected by later changes of the InventoryData.
+func (inventory *InventoryData) Snapshot() *Inventory {
+	return &Inventory{data: inventory.Clone()}
+}
+
+// InventoryManager holds the current Inventory for goroutines sharing it, to be replaced atomically when
+// a reloaded inventory becomes available. Readers keep using the Inventory they got until they call Current again.
+type InventoryManager struct {
+	current atomic.Pointer[Inventory]
+	// reloadMutex serializes Reload, so that a slow loading doesn't replace the result of a later one
+	reloadMutex sync.Mutex
+}
+
+// NewInventoryManager creates InventoryManager serving the initial Inventory, which may be nil until the first Swap
+func NewInventoryManager(initial *Inventory) *InventoryManager {
+	manager := &InventoryManager{}
+	manager.current.Store(initial)
+	return manager
+}
+
+// Current returns the latest Inventory
+func (manager *InventoryManager) Current() *Inventory {
+	return manager.current.Load()
+}
+
+// Swap replaces the current Inventory, returning the previous one
+func (manager *InventoryManager) Swap(inventory *Inventory) *Inventory {
+	return manager.current.Swap(inventory)
+}
+
+// Reload loads the inventory by LoadContext and makes it current if successful.
+// On error the current Inventory is kept and the error is returned.
+func (manager *InventoryManager) Reload(ctx context.Context, path string, options ...Option) (*Inventory, error) {
+	manager.reloadMutex.Lock()
+	defer manager.reloadMutex.Unlock()
+	inventory, err := LoadContext(ctx, path, options...)
+	if err != nil {
+		return nil, err
+	}
+	manager.current.Store(inventory)
+	return inventory, nil
+}
@@ -0,0 +1,78 @@
+package aini
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshot(t *testing.T) {
+	v := parseString(t, `
+	[web]
+	web1 env=prod
+	web2:2222
+	[db]
+	db1
+	[prod:children]
+	web
+	db
+	[prod:vars]
+	tier=1
+	`)
+	snapshot := v.Snapshot()
+
+	v.Hosts["web1"].Vars["env"] = "dev"
+	v.Groups["prod"].Vars["tier"] = "2"
+	delete(v.Hosts, "db1")
+	v.Groups["web"].Hosts["web3"] = &Host{Name: "web3"}
+
+	assert.Equal(t, "prod", snapshot.HostVars("web1")["env"])
+	assert.Equal(t, "1", snapshot.GroupVars("prod")["tier"])
+	assert.True(t, snapshot.HasHost("db1"))
+	assert.Equal(t, []string{"web1", "web2"}, snapshot.GroupHosts("web"))
+	assert.Equal(t, []string{"web", "prod", "all"}, snapshot.HostGroups("web1"))
+	port, _ := snapshot.HostPort("web2")
+	assert.Equal(t, 2222, port)
+	hosts, err := snapshot.MatchHostsByPatterns("prod:!web")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"db1"}, hosts)
+}
+
+func TestSnapshotConcurrentReads(t *testing.T) {
+	snapshot := parseString(t, "host[1:50] env=prod\n").Snapshot()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, name := range snapshot.HostNames() {
+				vars := snapshot.HostVars(name)
+				vars["env"] = "changed"
+				_, _ = snapshot.MatchHostsByPatterns("host1*")
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, "prod", snapshot.HostVars("host7")["env"])
+}
+
+func TestInventoryManager(t *testing.T) {
+	manager := NewInventoryManager(nil)
+	assert.Nil(t, manager.Current())
+
+	first := parseString(t, "host1\n").Snapshot()
+	assert.Nil(t, manager.Swap(first))
+	assert.Same(t, first, manager.Current())
+
+	reloaded, err := manager.Reload(context.Background(), "test_data/inventory")
+	assert.Nil(t, err)
+	assert.Same(t, reloaded, manager.Current())
+	assert.True(t, manager.Current().HasHost("Host7"))
+
+	// failed reloading keeps the current inventory
+	_, err = manager.Reload(context.Background(), "test_data/nosuchfile")
+	assert.NotNil(t, err)
+	assert.Same(t, reloaded, manager.Current())
+}
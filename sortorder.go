@@ -0,0 +1,119 @@
+package aini
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SortOrder is the order of hosts or groups returned by HostSet, GroupSet and the list helpers
+type SortOrder int
+
+const (
+	// LexicalOrder sorts by names compared as strings, the same as Ansible, e.g. web10 before web2
+	LexicalOrder SortOrder = iota
+	// NaturalOrder sorts by names with numbers compared by value, e.g. web2 before web10
+	NaturalOrder
+	// InventoryOrder sorts by the first appearance in the parsed inventory, and then by names for hosts or groups not from parsing
+	InventoryOrder
+)
+
+func (order SortOrder) String() string {
+	switch order {
+	case LexicalOrder:
+		return "lexical"
+	case NaturalOrder:
+		return "natural"
+	case InventoryOrder:
+		return "inventory"
+	}
+	return "unknown"
+}
+
+// ParseSortOrder parses names of SortOrder returned by String, e.g. "natural"
+func ParseSortOrder(name string) (SortOrder, error) {
+	for _, order := range []SortOrder{LexicalOrder, NaturalOrder, InventoryOrder} {
+		if name == order.String() {
+			return order, nil
+		}
+	}
+	return LexicalOrder, fmt.Errorf("unknown sort order \"%s\"", name)
+}
+
+// compareNames compares names in the order, InventoryOrder being compared as LexicalOrder
+func (order SortOrder) compareNames(a string, b string) int {
+	if order == NaturalOrder {
+		return compareNatural(a, b)
+	}
+	return strings.Compare(a, b)
+}
+
+// compareSequence compares items by their sequence numbers in the inventory, unnumbered items last,
+// and then by names
+func compareSequence[K comparable](sequence map[K]int, a K, b K, nameA string, nameB string) int {
+	seqA, okA := sequence[a]
+	seqB, okB := sequence[b]
+	switch {
+	case okA && okB && seqA != seqB:
+		if seqA < seqB {
+			return -1
+		}
+		return 1
+	case okA && !okB:
+		return -1
+	case !okA && okB:
+		return 1
+	}
+	return strings.Compare(nameA, nameB)
+}
+
+// compareNatural compares strings with runs of digits compared by numeric values.
+// Strings equal in that way, e.g. "web01" and "web1", are compared as plain strings.
+func compareNatural(a string, b string) int {
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if isDigit(a[i]) && isDigit(b[j]) {
+			endA, endB := digitsEnd(a, i), digitsEnd(b, j)
+			numA := strings.TrimLeft(a[i:endA], "0")
+			numB := strings.TrimLeft(b[j:endB], "0")
+			if len(numA) != len(numB) {
+				if len(numA) < len(numB) {
+					return -1
+				}
+				return 1
+			}
+			if c := strings.Compare(numA, numB); c != 0 {
+				return c
+			}
+			i, j = endA, endB
+			continue
+		}
+		if a[i] != b[j] {
+			if a[i] < b[j] {
+				return -1
+			}
+			return 1
+		}
+		i++
+		j++
+	}
+	switch {
+	case len(a)-i < len(b)-j:
+		return -1
+	case len(a)-i > len(b)-j:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// digitsEnd returns the end of the run of digits starting at start
+func digitsEnd(s string, start int) int {
+	end := start
+	for end < len(s) && isDigit(s[end]) {
+		end++
+	}
+	return end
+}
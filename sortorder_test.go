@@ -0,0 +1,35 @@
+package aini
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareNatural(t *testing.T) {
+	names := []string{"web10", "web2", "web1", "web01", "web", "db10a", "db10", "db9z", "web1-b", "host1.b", "host1.10", "host1.9"}
+	sort.Slice(names, func(i, j int) bool { return compareNatural(names[i], names[j]) < 0 })
+	assert.Equal(t, []string{"db9z", "db10", "db10a", "host1.9", "host1.10", "host1.b", "web", "web01", "web1", "web1-b", "web2", "web10"}, names)
+
+	assert.Equal(t, 0, compareNatural("web1", "web1"))
+	assert.Equal(t, -1, compareNatural("web9", "web10"))
+	assert.Equal(t, 1, compareNatural("web10", "web9"))
+	assert.Equal(t, -1, compareNatural("web99999999999999999999", "web100000000000000000000"))
+}
+
+func TestSortOrderString(t *testing.T) {
+	assert.Equal(t, "natural", NaturalOrder.String())
+	assert.Equal(t, "inventory", InventoryOrder.String())
+	assert.Equal(t, "unknown", SortOrder(-1).String())
+}
+
+func TestParseSortOrder(t *testing.T) {
+	for _, order := range []SortOrder{LexicalOrder, NaturalOrder, InventoryOrder} {
+		parsed, err := ParseSortOrder(order.String())
+		assert.Nil(t, err)
+		assert.Equal(t, order, parsed)
+	}
+	_, err := ParseSortOrder("random")
+	assert.NotNil(t, err)
+}
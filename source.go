@@ -0,0 +1,208 @@
+package aini
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// InventorySource is a plugin to load inventories of a certain format, similar to Ansible inventory plugins
+type InventorySource interface {
+	// CanParse quickly checks whether the file looks like something this source can parse, e.g. by extension
+	CanParse(path string) bool
+	// Parse loads hosts and groups from the file into the inventory, e.g. by AddHost, AddGroup, AddChildGroup and
+	// AddHostToGroup. Only direct relationships and inventory vars need to be set, the inventory is reconciled afterwards.
+	Parse(ctx context.Context, path string, inventory *InventoryData) error
+}
+
+// DefaultSourceOrder is the order in which sources are tried by ParseFileAuto if no order is given
+var DefaultSourceOrder = []string{"host_list", "ini", "yaml", "json", "script"}
+
+var sourceRegistry = struct {
+	sync.RWMutex
+	sources map[string]InventorySource
+}{
+	sources: map[string]InventorySource{
+		"host_list": hostListSource{},
+		"ini":       iniSource{},
+		"yaml":      yamlSource{},
+		"json":      jsonSource{},
+		"script":    scriptSource{},
+	},
+}
+
+// RegisterSource makes an inventory source available by the given name.
+// It panics if a source with the same name is already registered.
+func RegisterSource(name string, source InventorySource) {
+	sourceRegistry.Lock()
+	defer sourceRegistry.Unlock()
+	if source == nil {
+		panic("aini: RegisterSource source is nil")
+	}
+	if _, dup := sourceRegistry.sources[name]; dup {
+		panic("aini: RegisterSource called twice for source " + name)
+	}
+	sourceRegistry.sources[name] = source
+}
+
+// LookupSource returns the inventory source registered by the given name
+func LookupSource(name string) (InventorySource, bool) {
+	sourceRegistry.RLock()
+	defer sourceRegistry.RUnlock()
+	source, ok := sourceRegistry.sources[name]
+	return source, ok
+}
+
+// SourceNames returns names of all registered inventory sources in lexical order
+func SourceNames() []string {
+	sourceRegistry.RLock()
+	defer sourceRegistry.RUnlock()
+	names := make([]string, 0, len(sourceRegistry.sources))
+	for name := range sourceRegistry.sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SourceRejection describes why an inventory source didn't load a file
+type SourceRejection struct {
+	Source string
+	// Err is nil if the source didn't accept the file by CanParse
+	Err error
+}
+
+func (rejection SourceRejection) String() string {
+	if rejection.Err == nil {
+		return fmt.Sprintf("%s: not a supported file", rejection.Source)
+	}
+	return fmt.Sprintf("%s: %v", rejection.Source, rejection.Err)
+}
+
+// SourcesRejectedError is returned when none of inventory sources could load a file
+type SourcesRejectedError struct {
+	Path       string
+	Rejections []SourceRejection
+}
+
+func (err *SourcesRejectedError) Error() string {
+	reasons := make([]string, 0, len(err.Rejections))
+	for _, rejection := range err.Rejections {
+		reasons = append(reasons, rejection.String())
+	}
+	return fmt.Sprintf("failed to parse inventory %s with any source: %s", err.Path, strings.Join(reasons, "; "))
+}
+
+// ParseFileAuto parses inventory file or host list by trying inventory sources in the given order, DefaultSourceOrder if empty.
+// The result is from the first source which accepts and successfully parses the file.
+func ParseFileAuto(ctx context.Context, path string, sourceNames ...string) (*InventoryData, error) {
+	return ParseFileAutoWithOptions(ctx, path, ParseOptions{}, sourceNames...)
+}
+
+// ParseFileAutoWithOptions does the same as ParseFileAuto with limits applied to every source
+func ParseFileAutoWithOptions(ctx context.Context, path string, options ParseOptions, sourceNames ...string) (*InventoryData, error) {
+	if len(sourceNames) == 0 {
+		sourceNames = DefaultSourceOrder
+	}
+	if _, err := os.Stat(path); err != nil && !IsHostList(path) {
+		return &InventoryData{}, err
+	}
+
+	rejectedErr := &SourcesRejectedError{Path: path}
+	for _, name := range sourceNames {
+		source, ok := LookupSource(name)
+		if !ok {
+			return &InventoryData{}, fmt.Errorf("unknown inventory source: %s", name)
+		}
+		if !source.CanParse(path) {
+			rejectedErr.Rejections = append(rejectedErr.Rejections, SourceRejection{Source: name})
+			continue
+		}
+		inventory, err := ParseFileWithSourceOptions(ctx, path, source, options)
+		if err != nil {
+			if ctx.Err() != nil {
+				return inventory, ctx.Err()
+			}
+			if errors.Is(err, ErrLimitExceeded) {
+				// other sources are not tried with inputs over limits
+				return inventory, err
+			}
+			rejectedErr.Rejections = append(rejectedErr.Rejections, SourceRejection{Source: name, Err: err})
+			continue
+		}
+		return inventory, nil
+	}
+	return &InventoryData{}, rejectedErr
+}
+
+// ParseFileWithSource parses inventory file using the given inventory source
+func ParseFileWithSource(ctx context.Context, path string, source InventorySource) (*InventoryData, error) {
+	return ParseFileWithSourceOptions(ctx, path, source, ParseOptions{})
+}
+
+// ParseFileWithSourceOptions parses inventory file using the given inventory source with limits,
+// which are available to the source by InventoryData.ParseOptions
+func ParseFileWithSourceOptions(ctx context.Context, path string, source InventorySource, options ParseOptions) (*InventoryData, error) {
+	inventory := &InventoryData{path: path, parseOptions: options}
+	defer func() { inventory.parseOptions = ParseOptions{} }()
+	inventory.init()
+	if err := source.Parse(ctx, path, inventory); err != nil {
+		return inventory, err
+	}
+	if err := inventory.checkHostLimit(); err != nil {
+		return inventory, err
+	}
+	inventory.Reconcile()
+	return inventory, nil
+}
+
+// hasExtension checks whether the file extension is one of the given, case-insensitively
+func hasExtension(path string, extensions ...string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, e := range extensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// isRegularFile checks whether the path points to a regular file, following symlinks
+func isRegularFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// iniSource parses INI inventory files, the same as ParseFile
+type iniSource struct{}
+
+func (iniSource) CanParse(path string) bool {
+	if !isRegularFile(path) || hasExtension(path, ".yaml", ".yml", ".json") {
+		return false
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	// Scripts are left for the script source
+	head := make([]byte, 2)
+	n, _ := f.Read(head)
+	return !bytes.Equal(head[:n], []byte("#!"))
+}
+
+func (iniSource) Parse(ctx context.Context, path string, inventory *InventoryData) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return inventory.parse(ctx, bufio.NewReader(f), inventory.parseOptions)
+}
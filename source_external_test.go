@@ -0,0 +1,70 @@
+package aini_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/relex/aini"
+	"github.com/stretchr/testify/assert"
+)
+
+// testCMDBSource is a custom source using only the public API, as implemented outside of the package
+type testCMDBSource struct{}
+
+func (testCMDBSource) CanParse(path string) bool {
+	return filepath.Ext(path) == ".cmdb"
+}
+
+func (testCMDBSource) Parse(ctx context.Context, path string, inventory *aini.InventoryData) error {
+	inventory.AddHostToGroup("cmdb-host", "web")
+	inventory.AddHost("standalone").InventoryVars["rack"] = "r1"
+	inventory.AddChildGroup("cmdb", "web")
+	inventory.AddGroup("web").InventoryVars["http_port"] = "8080"
+	// groups made by hand without maps
+	inventory.AddHost("cmdb-host").DirectGroups["manual"] = &aini.Group{Name: "manual"}
+	return nil
+}
+
+func TestRegisterSource(t *testing.T) {
+	aini.RegisterSource("test-cmdb", testCMDBSource{})
+	assert.Contains(t, aini.SourceNames(), "test-cmdb")
+	assert.Panics(t, func() { aini.RegisterSource("test-cmdb", testCMDBSource{}) })
+
+	source, ok := aini.LookupSource("test-cmdb")
+	assert.True(t, ok)
+	v, err := aini.ParseFileWithSource(context.Background(), "hosts.cmdb", source)
+	assert.Nil(t, err)
+	assert.Contains(t, v.Groups["cmdb"].Hosts, "cmdb-host")
+	assert.Contains(t, v.Groups["all"].Hosts, "cmdb-host")
+	assert.Contains(t, v.Groups["manual"].Hosts, "cmdb-host")
+	assert.Contains(t, v.Groups["manual"].Parents, "all")
+	assert.Equal(t, "8080", v.Hosts["cmdb-host"].Vars["http_port"])
+	assert.NotContains(t, v.Groups["ungrouped"].Hosts, "cmdb-host")
+	assert.Contains(t, v.Groups["ungrouped"].Hosts, "standalone")
+	assert.Equal(t, "r1", v.Hosts["standalone"].Vars["rack"])
+}
+
+type testManyHostsSource struct{}
+
+func (testManyHostsSource) CanParse(path string) bool {
+	return true
+}
+
+func (testManyHostsSource) Parse(ctx context.Context, path string, inventory *aini.InventoryData) error {
+	for i := 0; i < 10; i++ {
+		inventory.AddHost(fmt.Sprintf("host%d", i))
+	}
+	return nil
+}
+
+func TestParseOptionsOfCustomSource(t *testing.T) {
+	_, err := aini.ParseFileWithSourceOptions(context.Background(), "hosts", testManyHostsSource{}, aini.ParseOptions{MaxHosts: 5})
+	assert.True(t, errors.Is(err, aini.ErrLimitExceeded))
+	v, err := aini.ParseFileWithSourceOptions(context.Background(), "hosts", testManyHostsSource{}, aini.ParseOptions{MaxHosts: 10})
+	assert.Nil(t, err)
+	assert.Len(t, v.Hosts, 10)
+	assert.Equal(t, aini.ParseOptions{}, v.ParseOptions())
+}
@@ -0,0 +1,163 @@
+package aini
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// jsonSource parses JSON files in the output format of inventory scripts and `ansible-inventory --list`
+//
+// e.g.
+//
+//	{
+//	    "web": {"hosts": ["web1", "web2"], "vars": {"http_port": 80}, "children": ["nginx"]},
+//	    "db": ["db1"],
+//	    "_meta": {"hostvars": {"web1": {"ansible_host": "10.0.0.1"}}}
+//	}
+type jsonSource struct{}
+
+func (jsonSource) CanParse(path string) bool {
+	return isRegularFile(path) && hasExtension(path, ".json")
+}
+
+func (jsonSource) Parse(ctx context.Context, path string, inventory *InventoryData) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := inventory.parseOptions.checkLineLength(data); err != nil {
+		return err
+	}
+	_, err = inventory.parseScriptOutput(ctx, data)
+	return err
+}
+
+// scriptSource runs executable inventory scripts with `--list` and parses their output as jsonSource.
+// If the output has no `_meta.hostvars`, the script is called again with `--host <hostname>` for each host.
+type scriptSource struct{}
+
+func (scriptSource) CanParse(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular() && info.Mode().Perm()&0o111 != 0
+}
+
+func (scriptSource) Parse(ctx context.Context, path string, inventory *InventoryData) error {
+	output, err := runInventoryScript(ctx, path, "--list")
+	if err != nil {
+		return err
+	}
+	hasMeta, err := inventory.parseScriptOutput(ctx, output)
+	if err != nil {
+		return fmt.Errorf("invalid output of %s --list: %w", path, err)
+	}
+	if hasMeta {
+		return nil
+	}
+	for _, host := range HostMapListValues(inventory.Hosts) {
+		output, err := runInventoryScript(ctx, path, "--host", host.Name)
+		if err != nil {
+			return err
+		}
+		var vars map[string]interface{}
+		if err := json.Unmarshal(output, &vars); err != nil {
+			return fmt.Errorf("invalid output of %s --host %s: %w", path, host.Name, err)
+		}
+		if err := addVarValues(host.InventoryVars, vars); err != nil {
+			return fmt.Errorf("host %s: %w", host.Name, err)
+		}
+	}
+	return nil
+}
+
+func runInventoryScript(ctx context.Context, path string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to run %s %s: %w: %s", path, strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// parseScriptOutput parses inventory in JSON format of inventory scripts. It returns whether `_meta.hostvars` is present.
+func (inventory *InventoryData) parseScriptOutput(ctx context.Context, data []byte) (bool, error) {
+	var groups map[string]json.RawMessage
+	if err := json.Unmarshal(data, &groups); err != nil {
+		return false, err
+	}
+
+	for name, groupData := range groups {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+		if name == "_meta" {
+			continue
+		}
+		group := inventory.getOrCreateGroup(name)
+		// hosts directly in "all" are ungrouped, as in the INI format
+		hostGroup := group
+		if name == "all" {
+			hostGroup = inventory.getOrCreateGroup("ungrouped")
+		}
+
+		var hostNames []string
+		if err := json.Unmarshal(groupData, &hostNames); err != nil {
+			var groupDef struct {
+				Hosts    []string               `json:"hosts"`
+				Vars     map[string]interface{} `json:"vars"`
+				Children []string               `json:"children"`
+			}
+			if err := json.Unmarshal(groupData, &groupDef); err != nil {
+				return false, fmt.Errorf("group %s: %w", name, err)
+			}
+			hostNames = groupDef.Hosts
+			if err := addVarValues(group.InventoryVars, groupDef.Vars); err != nil {
+				return false, fmt.Errorf("group %s: %w", name, err)
+			}
+			for _, childName := range groupDef.Children {
+				child := inventory.getOrCreateGroup(childName)
+				child.DirectParents[group.Name] = group
+			}
+		}
+		for _, hostName := range hostNames {
+			host := inventory.getOrCreateHost(hostName)
+			host.DirectGroups[hostGroup.Name] = hostGroup
+		}
+	}
+	for _, host := range inventory.Hosts {
+		if len(host.DirectGroups) > 1 {
+			delete(host.DirectGroups, "ungrouped")
+		}
+	}
+	// checked before scripts are called for every host
+	if err := inventory.checkHostLimit(); err != nil {
+		return false, err
+	}
+
+	metaData, hasMeta := groups["_meta"]
+	if !hasMeta {
+		return false, nil
+	}
+	var meta struct {
+		HostVars map[string]map[string]interface{} `json:"hostvars"`
+	}
+	if err := json.Unmarshal(metaData, &meta); err != nil {
+		return false, fmt.Errorf("_meta: %w", err)
+	}
+	for hostName, vars := range meta.HostVars {
+		host, ok := inventory.Hosts[hostName]
+		if !ok {
+			continue
+		}
+		if err := addVarValues(host.InventoryVars, vars); err != nil {
+			return false, fmt.Errorf("host %s: %w", hostName, err)
+		}
+	}
+	return meta.HostVars != nil, nil
+}
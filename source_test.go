@@ -0,0 +1,88 @@
+package aini
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func assertSourcesInventory(t *testing.T, v *InventoryData) {
+	assert.Contains(t, v.Groups["ungrouped"].Hosts, "gateway")
+	assert.Len(t, v.Groups["ungrouped"].Hosts, 1)
+	assert.Equal(t, "10.0.0.1", v.Hosts["gateway"].Vars["ansible_host"])
+	assert.Equal(t, "ntp.example.com", v.Hosts["web01"].Vars["ntp_server"])
+	assert.Equal(t, "80", v.Hosts["web02"].Vars["http_port"])
+	assert.Contains(t, v.Groups["web"].Children, "nginx")
+	assert.Contains(t, v.Groups["web"].Hosts, "web01")
+	assert.Contains(t, v.Hosts["web01"].Groups, "nginx")
+}
+
+func TestParseFileAuto(t *testing.T) {
+	ctx := context.Background()
+
+	v, err := ParseFileAuto(ctx, "test_data/sources/inventory.yml")
+	assert.Nil(t, err)
+	assertSourcesInventory(t, v)
+	assert.Equal(t, 2222, v.Hosts["web03"].Port)
+	assert.Equal(t, "8080", v.Hosts["web03"].Vars["http_port"])
+
+	v, err = ParseFileAuto(ctx, "test_data/sources/inventory.json")
+	assert.Nil(t, err)
+	assertSourcesInventory(t, v)
+	assert.Equal(t, `["a","b"]`, v.Hosts["gateway"].Vars["tags"])
+
+	v, err = ParseFileAuto(ctx, "test_data/sources/inventory.sh")
+	assert.Nil(t, err)
+	assert.Len(t, v.Groups["web"].Hosts, 2)
+	assert.Equal(t, "80", v.Hosts["web01"].Vars["http_port"])
+	assert.Equal(t, "web02", v.Hosts["web02"].Vars["inventory_name"])
+
+	v, err = ParseFileAuto(ctx, "test_data/sources/inventory.ini")
+	assert.Nil(t, err)
+	assert.Len(t, v.Groups["web"].Hosts, 2)
+
+	_, err = ParseFileAuto(ctx, "test_data/sources/inventory.ini", "yaml", "json")
+	var rejectedErr *SourcesRejectedError
+	if assert.True(t, errors.As(err, &rejectedErr)) {
+		assert.Len(t, rejectedErr.Rejections, 2)
+		assert.Equal(t, "yaml", rejectedErr.Rejections[0].Source)
+		assert.Nil(t, rejectedErr.Rejections[0].Err)
+	}
+
+	_, err = ParseFileAuto(ctx, "test_data/sources/broken.yml")
+	if assert.True(t, errors.As(err, &rejectedErr)) {
+		assert.Len(t, rejectedErr.Rejections, 5)
+		assert.Equal(t, "yaml", rejectedErr.Rejections[2].Source)
+		assert.NotNil(t, rejectedErr.Rejections[2].Err)
+	}
+
+	v, err = ParseFileAuto(ctx, "web01,web02")
+	assert.Nil(t, err)
+	assert.Len(t, v.Groups["ungrouped"].Hosts, 2)
+
+	_, err = ParseFileAuto(ctx, "test_data/sources/nosuchfile")
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+
+	_, err = ParseFileAuto(ctx, "test_data/sources/inventory.ini", "nosuchsource")
+	assert.NotNil(t, err)
+}
+
+func TestParseSourcesCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for name, path := range map[string]string{
+		"ini":       "test_data/sources/inventory.ini",
+		"yaml":      "test_data/sources/inventory.yml",
+		"json":      "test_data/sources/inventory.json",
+		"script":    "test_data/sources/inventory.sh",
+		"host_list": "web[01:99],gateway",
+	} {
+		source, ok := LookupSource(name)
+		assert.True(t, ok, name)
+		_, err := ParseFileWithSource(ctx, path, source)
+		assert.True(t, errors.Is(err, context.Canceled), name)
+	}
+}
@@ -0,0 +1,150 @@
+package aini
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlSource parses inventories in YAML format, as Ansible's `yaml` inventory plugin
+//
+// e.g.
+//
+//	all:
+//	  hosts:
+//	    host1:
+//	      ansible_port: 2222
+//	  children:
+//	    web:
+//	      hosts:
+//	        web[01:03]:
+//	      vars:
+//	        http_port: 80
+type yamlSource struct{}
+
+func (yamlSource) CanParse(path string) bool {
+	return isRegularFile(path) && hasExtension(path, "", ".yaml", ".yml", ".json")
+}
+
+func (yamlSource) Parse(ctx context.Context, path string, inventory *InventoryData) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := inventory.parseOptions.checkLineLength(data); err != nil {
+		return err
+	}
+	var groups map[string]interface{}
+	if err := yaml.Unmarshal(data, &groups); err != nil {
+		return err
+	}
+	if len(groups) == 0 {
+		return fmt.Errorf("parsed empty YAML file")
+	}
+	for name, groupData := range groups {
+		if err := inventory.parseYAMLGroup(ctx, name, groupData); err != nil {
+			return err
+		}
+	}
+	for _, host := range inventory.Hosts {
+		if len(host.DirectGroups) > 1 {
+			delete(host.DirectGroups, "ungrouped")
+		}
+	}
+	return nil
+}
+
+func (inventory *InventoryData) parseYAMLGroup(ctx context.Context, name string, data interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	group := inventory.getOrCreateGroup(name)
+	if data == nil {
+		return nil
+	}
+	sections, ok := data.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("group %s: expected a mapping with hosts, vars or children, got %T", name, data)
+	}
+	for key, section := range sections {
+		if section == nil {
+			continue
+		}
+		entries, ok := section.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("group %s: %s must be a mapping, got %T", name, key, section)
+		}
+		switch key {
+		case "hosts":
+			// hosts directly in "all" are ungrouped, as in the INI format
+			hostGroup := group
+			if name == "all" {
+				hostGroup = inventory.getOrCreateGroup("ungrouped")
+			}
+			for pattern, hostVars := range entries {
+				if err := inventory.parseYAMLHosts(ctx, pattern, hostVars, hostGroup); err != nil {
+					return fmt.Errorf("group %s: %w", name, err)
+				}
+			}
+		case "vars":
+			if err := addVarValues(group.InventoryVars, entries); err != nil {
+				return fmt.Errorf("group %s: %w", name, err)
+			}
+		case "children":
+			for childName, childData := range entries {
+				if err := inventory.parseYAMLGroup(ctx, childName, childData); err != nil {
+					return err
+				}
+				child := inventory.Groups[childName]
+				child.DirectParents[group.Name] = group
+			}
+		default:
+			return fmt.Errorf("group %s: invalid key '%s', expected hosts, vars or children", name, key)
+		}
+	}
+	return nil
+}
+
+func (inventory *InventoryData) parseYAMLHosts(ctx context.Context, pattern string, data interface{}, group *Group) error {
+	vars := map[string]interface{}{}
+	if data != nil {
+		var ok bool
+		if vars, ok = data.(map[string]interface{}); !ok {
+			return fmt.Errorf("host %s: vars must be a mapping, got %T", pattern, data)
+		}
+	}
+	hostpattern, port, portSet, err := getHostPort(pattern)
+	if err != nil {
+		return err
+	}
+	hostnames, err := expandHostPatternContext(ctx, hostpattern, inventory.parseOptions.expansionLimit())
+	if err != nil {
+		return err
+	}
+	for _, hostname := range hostnames {
+		host := inventory.getOrCreateHost(hostname)
+		if portSet {
+			host.Port = port
+			host.portSet = true
+		}
+		host.DirectGroups[group.Name] = group
+		if err := addVarValues(host.InventoryVars, vars); err != nil {
+			return fmt.Errorf("host %s: %w", hostname, err)
+		}
+	}
+	return inventory.checkHostLimit()
+}
+
+// addVarValues converts decoded variables to strings and adds them to `to` map
+func addVarValues(to map[string]string, from map[string]interface{}) error {
+	for k, v := range from {
+		value, err := varValueToString(v)
+		if err != nil {
+			return err
+		}
+		to[k] = value
+	}
+	return nil
+}
@@ -0,0 +1,6 @@
+#!/bin/sh
+if [ "$1" = "--list" ]; then
+    echo '{"web": {"hosts": ["web01", "web02"], "vars": {"http_port": 80}}}'
+elif [ "$1" = "--host" ]; then
+    echo "{\"inventory_name\": \"$2\"}"
+fi
@@ -0,0 +1,41 @@
+#!/usr/bin/env python3
+# Generates vault fixtures in the format of `ansible-vault encrypt` and `ansible-vault encrypt_string` (1.1, AES256),
+# independently of aini: PBKDF2 and HMAC from Python's hashlib and hmac, AES-256-CTR from the openssl command.
+#
+# Password: golden-pass
+# Check with: ansible-vault view --vault-password-file <(echo golden-pass) group_vars/web.yml
+import binascii
+import hashlib
+import hmac
+import os
+import subprocess
+
+PASSWORD = b"golden-pass"
+
+
+def encrypt(plaintext: bytes) -> str:
+    salt = os.urandom(32)
+    derived = hashlib.pbkdf2_hmac("sha256", PASSWORD, salt, 10000, 2 * 32 + 16)
+    key1, key2, iv = derived[:32], derived[32:64], derived[64:]
+    padding = 16 - len(plaintext) % 16
+    padded = plaintext + bytes([padding]) * padding
+    ciphertext = subprocess.run(
+        ["openssl", "enc", "-aes-256-ctr", "-nopad", "-K", key1.hex(), "-iv", iv.hex()],
+        input=padded, capture_output=True, check=True).stdout
+    mac = hmac.new(key2, ciphertext, hashlib.sha256).hexdigest().encode()
+    envelope = binascii.hexlify(b"\n".join([binascii.hexlify(salt), mac, binascii.hexlify(ciphertext)])).decode()
+    lines = ["$ANSIBLE_VAULT;1.1;AES256"] + [envelope[i:i + 80] for i in range(0, len(envelope), 80)]
+    return "\n".join(lines) + "\n"
+
+
+def main():
+    here = os.path.dirname(os.path.abspath(__file__))
+    with open(os.path.join(here, "group_vars", "web.yml"), "w") as f:
+        f.write(encrypt(b"db_password: s3cret\n"))
+    inline = encrypt(b"inline-secret").strip().replace("\n", "\n          ")
+    with open(os.path.join(here, "host_vars", "host1.yml"), "w") as f:
+        f.write("plain: value\napi_key: !vault |\n          " + inline + "\n")
+
+
+if __name__ == "__main__":
+    main()
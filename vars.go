@@ -16,26 +16,117 @@ import (
 // AddVars take a path that contains group_vars and host_vars directories
 // and adds these variables to the InventoryData
 func (inventory *InventoryData) AddVars(path string) error {
-	return inventory.doAddVars(path, false)
+	return inventory.doAddVars(path, varsOptions{})
 }
 
 // AddVarsLowerCased does the same as AddVars, but converts hostnames and groups name to lowercase.
 // Use this function if you've executed `inventory.HostsToLower` or `inventory.GroupsToLower`
 func (inventory *InventoryData) AddVarsLowerCased(path string) error {
-	return inventory.doAddVars(path, true)
+	return inventory.doAddVars(path, varsOptions{lowercased: true})
 }
 
-func (inventory *InventoryData) doAddVars(path string, lowercased bool) error {
-	_, err := os.Stat(path)
+// AddVarsStrict does the same as AddVars, but fails with VarsLoadError listing all files which cannot be loaded,
+// instead of skipping them silently. Variables from the rest of files are still added.
+func (inventory *InventoryData) AddVarsStrict(path string) error {
+	return inventory.doAddVars(path, varsOptions{strict: true})
+}
+
+// AddVarsLenient does the same as AddVars, but returns problems of skipped files as warnings
+func (inventory *InventoryData) AddVarsLenient(path string) ([]*VarsFileError, error) {
+	return inventory.loadVars(path, varsOptions{})
+}
+
+// VarsFileError describes a problem of a file in group_vars or host_vars
+type VarsFileError struct {
+	Path string
+	// Line is the line number of the problem in the file, 0 if unknown
+	Line int
+	Err  error
+}
+
+func (err *VarsFileError) Error() string {
+	if err.Line > 0 {
+		return fmt.Sprintf("%s:%d: %v", err.Path, err.Line, err.Err)
+	}
+	return fmt.Sprintf("%s: %v", err.Path, err.Err)
+}
+
+func (err *VarsFileError) Unwrap() error {
+	return err.Err
+}
+
+// VarsLoadError is returned in strict mode when any files in group_vars or host_vars cannot be loaded
+type VarsLoadError struct {
+	Errors []*VarsFileError
+}
+
+func (err *VarsLoadError) Error() string {
+	messages := make([]string, 0, len(err.Errors))
+	for _, fileErr := range err.Errors {
+		messages = append(messages, fileErr.Error())
+	}
+	return fmt.Sprintf("failed to load %d vars file(s): %s", len(err.Errors), strings.Join(messages, "; "))
+}
+
+func (err *VarsLoadError) Unwrap() []error {
+	errs := make([]error, 0, len(err.Errors))
+	for _, fileErr := range err.Errors {
+		errs = append(errs, fileErr)
+	}
+	return errs
+}
+
+// varsRoot is a directory which group_vars and host_vars were loaded from
+type varsRoot struct {
+	path       string
+	lowercased bool
+}
+
+// varsOptions controls loading of group_vars and host_vars
+type varsOptions struct {
+	// lowercased converts names of vars files to lowercase
+	lowercased bool
+	// caseInsensitive matches names of vars files to lowercased names of hosts and groups, keeping their names
+	caseInsensitive bool
+	// strict returns problems of reading and parsing vars files as VarsLoadError instead of ignoring them
+	strict bool
+	// vaultPasswords are used to decrypt vaulted files and values
+	vaultPasswords []string
+}
+
+func (inventory *InventoryData) doAddVars(path string, options varsOptions) error {
+	problems, err := inventory.loadVars(path, options)
 	if err != nil {
 		return err
 	}
-	walk(path, "group_vars", inventory.getGroupsMap(), lowercased)
-	walk(path, "host_vars", inventory.getHostsMap(), lowercased)
-	inventory.reconcileVars()
+	if options.strict && len(problems) > 0 {
+		return &VarsLoadError{Errors: problems}
+	}
 	return nil
 }
 
+// loadVars adds variables from all loadable files and returns problems of the rest.
+// The error is only returned if the path itself cannot be used.
+func (inventory *InventoryData) loadVars(path string, options varsOptions) ([]*VarsFileError, error) {
+	_, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if inventory.caseInsensitive {
+		if err := inventory.checkCaseConflicts(); err != nil {
+			return nil, err
+		}
+		options.caseInsensitive = true
+	}
+	inventory.varsRoots = append(inventory.varsRoots, varsRoot{path: path, lowercased: options.lowercased || options.caseInsensitive})
+	var problems []*VarsFileError
+	walk(path, "group_vars", inventory.getGroupsMap(options.caseInsensitive), options, &problems)
+	walk(path, "host_vars", inventory.getHostsMap(options.caseInsensitive), options, &problems)
+	inventory.varsProblems = append(inventory.varsProblems, problems...)
+	inventory.reconcileVars()
+	return problems, nil
+}
+
 type fileVarsGetter interface {
 	getFileVars() map[string]string
 }
@@ -48,60 +139,76 @@ func (group *Group) getFileVars() map[string]string {
 	return group.FileVars
 }
 
-func (inventory InventoryData) getHostsMap() map[string]fileVarsGetter {
+func (inventory InventoryData) getHostsMap(lowercased bool) map[string]fileVarsGetter {
 	result := make(map[string]fileVarsGetter, len(inventory.Hosts))
 	for k, v := range inventory.Hosts {
+		if lowercased {
+			k = strings.ToLower(k)
+		}
 		result[k] = v
 	}
 	return result
 }
 
-func (inventory InventoryData) getGroupsMap() map[string]fileVarsGetter {
+func (inventory InventoryData) getGroupsMap(lowercased bool) map[string]fileVarsGetter {
 	result := make(map[string]fileVarsGetter, len(inventory.Groups))
 	for k, v := range inventory.Groups {
+		if lowercased {
+			k = strings.ToLower(k)
+		}
 		result[k] = v
 	}
 	return result
 }
 
-func walk(root string, subdir string, m map[string]fileVarsGetter, lowercased bool) error {
+func walk(root string, subdir string, m map[string]fileVarsGetter, options varsOptions, problems *[]*VarsFileError) {
 	path := filepath.Join(root, subdir)
 	_, err := os.Stat(path)
 	// If the dir doesn't exist we can just skip it
 	if err != nil {
-		return nil
+		return
 	}
-	f := getWalkerFn(path, m, lowercased)
-	return filepath.WalkDir(path, f)
+	f := getWalkerFn(path, m, options, problems)
+	// The walker never fails, problems are collected instead
+	_ = filepath.WalkDir(path, f)
 }
 
-func getWalkerFn(root string, m map[string]fileVarsGetter, lowercased bool) fs.WalkDirFunc {
+func getWalkerFn(root string, m map[string]fileVarsGetter, options varsOptions, problems *[]*VarsFileError) fs.WalkDirFunc {
 	var currentVars map[string]string
 	return func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
+			*problems = append(*problems, &VarsFileError{Path: path, Err: err})
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
 			return nil
 		}
 		if filepath.Dir(path) == root {
 			filename := filepath.Base(path)
 			ext := filepath.Ext(path)
 			itemName := strings.TrimSuffix(filename, ext)
-			if lowercased {
+			if options.lowercased || options.caseInsensitive {
 				itemName = strings.ToLower(itemName)
 			}
 			if currentItem, ok := m[itemName]; ok {
 				currentVars = currentItem.getFileVars()
 			} else {
+				currentVars = nil
 				return nil
 			}
 		}
 		if d.IsDir() {
 			return nil
 		}
-		return addVarsFromFile(currentVars, path)
+		if err := addVarsFromFile(currentVars, path, options.vaultPasswords); err != nil {
+			*problems = append(*problems, err)
+		}
+		return nil
 	}
 }
 
-func addVarsFromFile(currentVars map[string]string, path string) error {
+// addVarsFromFile adds variables from the YAML file if it's for an existing host or group. Nothing is added on errors.
+func addVarsFromFile(currentVars map[string]string, path string, vaultPasswords []string) *VarsFileError {
 	if currentVars == nil {
 		// Group or Host doesn't exist in the inventory, ignoring
 		return nil
@@ -112,32 +219,88 @@ func addVarsFromFile(currentVars map[string]string, path string) error {
 	}
 	f, err := ioutil.ReadFile(path)
 	if err != nil {
-		return err
+		return &VarsFileError{Path: path, Err: err}
 	}
-	vars := make(map[string]interface{})
-	err = yaml.Unmarshal(f, &vars)
+	if isVaultData(f) {
+		if f, err = decryptVault(f, vaultPasswords); err != nil {
+			return &VarsFileError{Path: path, Err: err}
+		}
+	}
+	var doc yaml.Node
+	err = yaml.Unmarshal(f, &doc)
 	if err != nil {
-		return err
+		return &VarsFileError{Path: path, Err: err}
+	}
+	if doc.Kind == 0 {
+		// Empty file
+		return nil
+	}
+	top := doc.Content[0]
+	if top.Kind != yaml.MappingNode && !(top.Kind == yaml.ScalarNode && top.Tag == "!!null") {
+		return &VarsFileError{Path: path, Line: top.Line, Err: fmt.Errorf("top-level value must be a mapping")}
+	}
+	if key := findDuplicateKey(top); key != nil {
+		return &VarsFileError{Path: path, Line: key.Line, Err: fmt.Errorf("duplicate key '%s'", key.Value)}
 	}
+	if err := decryptVaultNodes(&doc, vaultPasswords); err != nil {
+		return &VarsFileError{Path: path, Err: err}
+	}
+	vars := make(map[string]interface{})
+	if err := doc.Decode(&vars); err != nil {
+		return &VarsFileError{Path: path, Err: err}
+	}
+	values := make(map[string]string, len(vars))
 	for k, v := range vars {
-		switch v := v.(type) {
-		case string:
-			currentVars[k] = v
-		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
-			currentVars[k] = fmt.Sprint(v)
-		case bool:
-			currentVars[k] = strconv.FormatBool(v)
-		default:
-			data, err := json.Marshal(v)
-			if err != nil {
-				return err
+		value, err := varValueToString(v)
+		if err != nil {
+			return &VarsFileError{Path: path, Err: fmt.Errorf("variable '%s': %w", k, err)}
+		}
+		values[k] = value
+	}
+	addValues(currentVars, values)
+	return nil
+}
+
+// findDuplicateKey returns the first repeated key of all mappings in the node tree, or nil
+func findDuplicateKey(node *yaml.Node) *yaml.Node {
+	if node.Kind == yaml.MappingNode {
+		seen := make(map[string]bool, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			if key.Kind == yaml.ScalarNode && key.Tag != "!!merge" {
+				if seen[key.Value] {
+					return key
+				}
+				seen[key.Value] = true
 			}
-			currentVars[k] = string(data)
+		}
+	}
+	for _, child := range node.Content {
+		if key := findDuplicateKey(child); key != nil {
+			return key
 		}
 	}
 	return nil
 }
 
+// varValueToString converts decoded YAML or JSON value to variable string. Lists and objects are encoded in JSON.
+func varValueToString(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+}
+
 func (inventory *InventoryData) reconcileVars() {
 	/*
 		Priority of variables is defined here: https://docs.ansible.com/ansible/latest/user_guide/playbooks_variables.html#understanding-variable-precedence
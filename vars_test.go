@@ -1,6 +1,9 @@
 package aini
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -55,3 +58,68 @@ func TestAddVarsLowerCased(t *testing.T) {
 	assert.Equal(t, "string", v.Groups["tomcat"].Vars["tomcat_string_var"])
 	assert.Equal(t, "string", v.Hosts["host7"].Vars["host7_string_var"])
 }
+
+func writeVarsFiles(t *testing.T, files map[string]string) string {
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.Nil(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	return root
+}
+
+func TestAddVarsStrict(t *testing.T) {
+	root := writeVarsFiles(t, map[string]string{
+		"group_vars/web.yml":        "web_var: ok\n",
+		"group_vars/db.yml":         "key: [unclosed\n",
+		"group_vars/all.yml":        "- a\n- b\n",
+		"host_vars/host1.yml":       "x: 1\ny: 2\nx: 3\n",
+		"host_vars/host2/main.yml":  "nested:\n  a: 1\n  a: 2\n",
+		"host_vars/host2/other.yml": "host2_var: ok\n",
+		"host_vars/host3.yml":       "",
+		"host_vars/unknown.yml":     "key: [unclosed\n",
+	})
+	inventory := `
+	[web]
+	host1
+	host2
+	host3
+	[db]
+	host3
+	`
+
+	v := parseString(t, inventory)
+	err := v.AddVarsStrict(root)
+	var loadErr *VarsLoadError
+	assert.True(t, errors.As(err, &loadErr))
+	problems := make(map[string]int)
+	for _, fileErr := range loadErr.Errors {
+		rel, _ := filepath.Rel(root, fileErr.Path)
+		problems[rel] = fileErr.Line
+	}
+	assert.Equal(t, map[string]int{
+		"group_vars/all.yml":       1,
+		"group_vars/db.yml":        0,
+		"host_vars/host1.yml":      3,
+		"host_vars/host2/main.yml": 3,
+	}, problems)
+	assert.Contains(t, err.Error(), "duplicate key 'x'")
+	assert.Contains(t, err.Error(), "top-level value must be a mapping")
+	// the rest of files are still loaded
+	assert.Equal(t, "ok", v.Hosts["host1"].Vars["web_var"])
+	assert.Equal(t, "ok", v.Hosts["host2"].Vars["host2_var"])
+	assert.NotContains(t, v.Hosts["host1"].Vars, "x")
+
+	v = parseString(t, inventory)
+	warnings, err := v.AddVarsLenient(root)
+	assert.Nil(t, err)
+	assert.Len(t, warnings, 4)
+	assert.Equal(t, "ok", v.Hosts["host1"].Vars["web_var"])
+
+	v = parseString(t, inventory)
+	assert.Nil(t, v.AddVars(root))
+
+	_, err = v.AddVarsLenient(filepath.Join(root, "nosuchdir"))
+	assert.NotNil(t, err)
+}
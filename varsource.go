@@ -0,0 +1,79 @@
+package aini
+
+import "fmt"
+
+// Kinds of variable sources, in order of increasing precedence
+const (
+	VarSourceGroupInventory = "group"
+	VarSourceGroupVars      = "group_vars"
+	VarSourceHostInventory  = "host"
+	VarSourceHostVars       = "host_vars"
+)
+
+// VarSource tells where the resolved value of a variable is defined
+type VarSource struct {
+	// Kind is one of VarSourceGroupInventory, VarSourceGroupVars, VarSourceHostInventory and VarSourceHostVars
+	Kind string
+	// Name is the name of the host or group defining the value
+	Name string
+}
+
+func (source VarSource) String() string {
+	switch source.Kind {
+	case VarSourceGroupInventory:
+		return fmt.Sprintf("inventory vars of group %s", source.Name)
+	case VarSourceHostInventory:
+		return fmt.Sprintf("inventory line of host %s", source.Name)
+	default:
+		return fmt.Sprintf("%s/%s", source.Kind, source.Name)
+	}
+}
+
+// VarSource returns where the value of the variable in Vars comes from, following precedence of reconciliation
+func (host *Host) VarSource(name string) (VarSource, bool) {
+	if _, ok := host.FileVars[name]; ok {
+		return VarSource{Kind: VarSourceHostVars, Name: host.Name}, true
+	}
+	if _, ok := host.InventoryVars[name]; ok {
+		return VarSource{Kind: VarSourceHostInventory, Name: host.Name}, true
+	}
+	groups := GroupMapListValues(host.DirectGroups)
+	for i := len(groups) - 1; i >= 0; i-- {
+		if _, ok := groups[i].Vars[name]; ok {
+			return groups[i].VarSource(name)
+		}
+	}
+	return VarSource{}, false
+}
+
+// VarSource returns where the value of the variable in Vars comes from, following precedence of reconciliation
+func (group *Group) VarSource(name string) (VarSource, bool) {
+	if _, ok := group.AllFileVars[name]; ok || group.AllFileVars == nil {
+		if origin := group.varOrigin(name, func(g *Group) (map[string]string, map[string]string) { return g.FileVars, g.AllFileVars }); origin != nil {
+			return VarSource{Kind: VarSourceGroupVars, Name: origin.Name}, true
+		}
+	}
+	if origin := group.varOrigin(name, func(g *Group) (map[string]string, map[string]string) { return g.InventoryVars, g.AllInventoryVars }); origin != nil {
+		return VarSource{Kind: VarSourceGroupInventory, Name: origin.Name}, true
+	}
+	return VarSource{}, false
+}
+
+// varOrigin finds the group defining the variable among the group and its ancestors,
+// given own and projected variables of a group, the later parent taking precedence as in populateInventoryVars
+func (group *Group) varOrigin(name string, vars func(*Group) (map[string]string, map[string]string)) *Group {
+	own, _ := vars(group)
+	if _, ok := own[name]; ok {
+		return group
+	}
+	parents := GroupMapListValues(group.DirectParents)
+	for i := len(parents) - 1; i >= 0; i-- {
+		_, all := vars(parents[i])
+		if _, ok := all[name]; ok || all == nil {
+			if origin := parents[i].varOrigin(name, vars); origin != nil {
+				return origin
+			}
+		}
+	}
+	return nil
+}
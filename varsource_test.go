@@ -0,0 +1,35 @@
+package aini
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVarSource(t *testing.T) {
+	v, err := ParseFile("test_data/inventory")
+	assert.Nil(t, err)
+	assert.Nil(t, v.AddVars("test_data"))
+
+	host1 := v.Hosts["host1"]
+	cases := map[string]VarSource{
+		"host1_string_var":           {Kind: VarSourceHostVars, Name: "host1"},
+		"host1_inventory_string_var": {Kind: VarSourceHostInventory, Name: "host1"},
+		"nginx_string_var":           {Kind: VarSourceGroupVars, Name: "nginx"},
+		"web_string_var":             {Kind: VarSourceGroupVars, Name: "web"},
+		"web_inventory_string_var":   {Kind: VarSourceGroupInventory, Name: "web"},
+	}
+	for name, expected := range cases {
+		source, ok := host1.VarSource(name)
+		assert.True(t, ok, name)
+		assert.Equal(t, expected, source, name)
+	}
+	_, ok := host1.VarSource("nosuchvar")
+	assert.False(t, ok)
+
+	source, ok := v.Groups["apache"].VarSource("web_inventory_string_var")
+	assert.True(t, ok)
+	assert.Equal(t, "inventory vars of group web", source.String())
+	assert.Equal(t, "group_vars/web", VarSource{Kind: VarSourceGroupVars, Name: "web"}.String())
+	assert.Equal(t, "inventory line of host host1", VarSource{Kind: VarSourceHostInventory, Name: "host1"}.String())
+}
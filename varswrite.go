@@ -0,0 +1,361 @@
+package aini
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// VarsWriteOptions controls writing of variables to group_vars and host_vars files
+type VarsWriteOptions struct {
+	// Root is the directory containing group_vars and host_vars for new files,
+	// by default the last directory which vars were loaded from
+	Root string
+	// DefaultFile is the path of the file for variables not defined in any file yet, relative to group_vars or
+	// host_vars with "{name}" replaced by the name of the host or group, e.g. "{name}/generated.yml".
+	// By default it's the last file of the host or group, or "{name}.yml" if there is none.
+	DefaultFile string
+}
+
+// SetHostVar sets a variable of the host in the host_vars file defining it, or the default file of options,
+// and reconciles the inventory. Comments and order of keys in the file are kept.
+//
+// The value is encoded to YAML, e.g. strings, numbers, bools, slices and maps. Vault-encrypted files are never modified.
+// It returns the path of the written file.
+func (inventory *InventoryData) SetHostVar(host *Host, name string, value any, options VarsWriteOptions) (string, error) {
+	if host.FileVars == nil {
+		host.FileVars = make(map[string]string)
+	}
+	return inventory.setFileVar("host_vars", host.Name, host.FileVars, name, value, options)
+}
+
+// SetGroupVar sets a variable of the group in the group_vars file defining it, or the default file of options,
+// like SetHostVar
+func (inventory *InventoryData) SetGroupVar(group *Group, name string, value any, options VarsWriteOptions) (string, error) {
+	if group.FileVars == nil {
+		group.FileVars = make(map[string]string)
+	}
+	return inventory.setFileVar("group_vars", group.Name, group.FileVars, name, value, options)
+}
+
+// DeleteHostVar removes a variable of the host from all host_vars files defining it and reconciles the inventory.
+// It returns paths of modified files, none if the variable is not defined in any file.
+func (inventory *InventoryData) DeleteHostVar(host *Host, name string) ([]string, error) {
+	return inventory.deleteFileVar("host_vars", host.Name, host.FileVars, name)
+}
+
+// DeleteGroupVar removes a variable of the group from all group_vars files defining it, like DeleteHostVar
+func (inventory *InventoryData) DeleteGroupVar(group *Group, name string) ([]string, error) {
+	return inventory.deleteFileVar("group_vars", group.Name, group.FileVars, name)
+}
+
+func (inventory *InventoryData) setFileVar(subdir string, itemName string, fileVars map[string]string, name string, value any, options VarsWriteOptions) (string, error) {
+	node, normalized, err := encodeVarValue(value)
+	if err != nil {
+		return "", fmt.Errorf("variable '%s': %w", name, err)
+	}
+	files, vaulted, err := inventory.readVarsFilesOf(subdir, itemName)
+	if err != nil {
+		return "", err
+	}
+
+	var target *varsFile
+	for _, file := range files {
+		if file.find(name) >= 0 {
+			target = file
+		}
+	}
+	if target == nil {
+		if _, ok := fileVars[name]; ok && vaulted {
+			return "", fmt.Errorf("variable '%s' of %s is defined in a vault-encrypted file", name, itemName)
+		}
+		if target, err = inventory.defaultVarsFile(subdir, itemName, files, options); err != nil {
+			return "", err
+		}
+	}
+
+	if index := target.find(name); index >= 0 {
+		existing := target.top.Content[index+1]
+		if existing.Tag == "!vault" {
+			return "", &VarsFileError{Path: target.path, Line: existing.Line, Err: fmt.Errorf("variable '%s' is encrypted by vault", name)}
+		}
+		node.LineComment, node.FootComment = existing.LineComment, existing.FootComment
+		target.top.Content[index+1] = node
+	} else {
+		key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name}
+		target.top.Content = append(target.top.Content, key, node)
+	}
+	if err := target.write(); err != nil {
+		return "", err
+	}
+	fileVars[name] = normalized
+	inventory.Reconcile()
+	return target.path, nil
+}
+
+func (inventory *InventoryData) deleteFileVar(subdir string, itemName string, fileVars map[string]string, name string) ([]string, error) {
+	files, vaulted, err := inventory.readVarsFilesOf(subdir, itemName)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, file := range files {
+		index := file.find(name)
+		if index < 0 {
+			continue
+		}
+		file.top.Content = append(file.top.Content[:index], file.top.Content[index+2:]...)
+		if err := file.write(); err != nil {
+			return paths, err
+		}
+		paths = append(paths, file.path)
+	}
+	if _, ok := fileVars[name]; ok && len(paths) == 0 && vaulted {
+		return nil, fmt.Errorf("variable '%s' of %s is defined in a vault-encrypted file", name, itemName)
+	}
+	delete(fileVars, name)
+	inventory.Reconcile()
+	return paths, nil
+}
+
+// encodeVarValue encodes the value to a YAML node, and returns it with the variable string the node is loaded as
+func encodeVarValue(value any) (*yaml.Node, string, error) {
+	node := &yaml.Node{}
+	if err := node.Encode(value); err != nil {
+		return nil, "", err
+	}
+	var decoded interface{}
+	if err := node.Decode(&decoded); err != nil {
+		return nil, "", err
+	}
+	normalized, err := varValueToString(decoded)
+	if err != nil {
+		return nil, "", err
+	}
+	return node, normalized, nil
+}
+
+// defaultVarsFile returns the file for a variable not defined in existing files of a host or group
+func (inventory *InventoryData) defaultVarsFile(subdir string, itemName string, files []*varsFile, options VarsWriteOptions) (*varsFile, error) {
+	if options.DefaultFile == "" && len(files) > 0 {
+		return files[len(files)-1], nil
+	}
+	if strings.ContainsAny(itemName, `/\`) {
+		return nil, fmt.Errorf("cannot make vars file of %s: invalid file name", itemName)
+	}
+	root := options.Root
+	if root == "" {
+		if len(inventory.varsRoots) == 0 {
+			return nil, fmt.Errorf("cannot make vars file of %s: no vars were loaded and Root is not set", itemName)
+		}
+		root = inventory.varsRoots[len(inventory.varsRoots)-1].path
+	}
+	relative := itemName + ".yml"
+	if options.DefaultFile != "" {
+		if !strings.Contains(options.DefaultFile, "{name}") {
+			return nil, fmt.Errorf("default vars file '%s' must contain {name}", options.DefaultFile)
+		}
+		relative = strings.ReplaceAll(options.DefaultFile, "{name}", itemName)
+	}
+	first, _, _ := strings.Cut(filepath.ToSlash(relative), "/")
+	if ext := filepath.Ext(relative); ext != ".yml" && ext != ".yaml" || strings.TrimSuffix(first, filepath.Ext(first)) != itemName {
+		return nil, fmt.Errorf("default vars file '%s' would not be loaded as vars of %s", options.DefaultFile, itemName)
+	}
+	path := filepath.Join(root, subdir, relative)
+	for _, file := range files {
+		if file.path == path {
+			return file, nil
+		}
+	}
+	inventory.addVarsRoot(varsRoot{path: root, lowercased: inventory.caseInsensitive})
+	if _, err := os.Stat(path); err == nil {
+		// the file is outside of loaded vars directories or vault-encrypted
+		return readVarsFile(path)
+	}
+	return newVarsFile(path), nil
+}
+
+// varsFile is a parsed group_vars or host_vars file to be modified
+type varsFile struct {
+	path string
+	mode os.FileMode
+	doc  *yaml.Node
+	// top is the top-level mapping of the document
+	top *yaml.Node
+	// header is the document start marker to keep, if any
+	header string
+	indent int
+}
+
+func newVarsFile(path string) *varsFile {
+	top := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	return &varsFile{
+		path:   path,
+		mode:   0o644,
+		doc:    &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{top}},
+		top:    top,
+		indent: 2,
+	}
+}
+
+// readVarsFilesOf reads files of the host or group in all vars directories, in order of loading.
+// Vault-encrypted files are skipped and reported by the second result.
+func (inventory *InventoryData) readVarsFilesOf(subdir string, itemName string) ([]*varsFile, bool, error) {
+	var files []*varsFile
+	vaulted := false
+	for _, root := range inventory.varsRoots {
+		paths, err := varsFilePaths(filepath.Join(root.path, subdir), itemName, root.lowercased)
+		if err != nil {
+			return nil, false, err
+		}
+		for _, path := range paths {
+			file, err := readVarsFile(path)
+			if errors.Is(err, errVaultedVarsFile) {
+				vaulted = true
+				continue
+			}
+			if err != nil {
+				return nil, false, err
+			}
+			files = append(files, file)
+		}
+	}
+	return files, vaulted, nil
+}
+
+// varsFilePaths lists YAML files of the host or group in the group_vars or host_vars directory,
+// matched the same way as loading vars and in the same order
+func varsFilePaths(dir string, itemName string, lowercased bool) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if lowercased {
+		itemName = strings.ToLower(itemName)
+	}
+	var paths []string
+	for _, entry := range entries {
+		entryName := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		if lowercased {
+			entryName = strings.ToLower(entryName)
+		}
+		if entryName != itemName {
+			continue
+		}
+		err := filepath.WalkDir(filepath.Join(dir, entry.Name()), func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if ext := filepath.Ext(path); !d.IsDir() && (ext == ".yml" || ext == ".yaml") {
+				paths = append(paths, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return paths, nil
+}
+
+var errVaultedVarsFile = errors.New("vars file is encrypted by vault")
+
+// readVarsFile parses the file for modification, failing on files which cannot be loaded as vars
+func readVarsFile(path string) (*varsFile, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, &VarsFileError{Path: path, Err: err}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, &VarsFileError{Path: path, Err: err}
+	}
+	if isVaultData(data) {
+		return nil, &VarsFileError{Path: path, Err: errVaultedVarsFile}
+	}
+	file := newVarsFile(path)
+	file.mode = stat.Mode().Perm()
+	file.indent = detectIndent(data)
+	if bytes.HasPrefix(data, []byte("---\n")) || bytes.HasPrefix(data, []byte("---\r\n")) {
+		file.header = "---\n"
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, &VarsFileError{Path: path, Err: err}
+	}
+	if doc.Kind == 0 {
+		// Empty file
+		return file, nil
+	}
+	top := doc.Content[0]
+	switch {
+	case top.Kind == yaml.ScalarNode && top.Tag == "!!null":
+		top = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", HeadComment: top.HeadComment, FootComment: top.FootComment}
+		doc.Content[0] = top
+	case top.Kind != yaml.MappingNode:
+		return nil, &VarsFileError{Path: path, Line: top.Line, Err: fmt.Errorf("top-level value must be a mapping")}
+	}
+	if key := findDuplicateKey(top); key != nil {
+		return nil, &VarsFileError{Path: path, Line: key.Line, Err: fmt.Errorf("duplicate key '%s'", key.Value)}
+	}
+	file.doc, file.top = &doc, top
+	return file, nil
+}
+
+// find returns the index of the key of the variable in the top-level mapping, or -1
+func (file *varsFile) find(name string) int {
+	for i := 0; i+1 < len(file.top.Content); i += 2 {
+		if key := file.top.Content[i]; key.Kind == yaml.ScalarNode && key.Value == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// write encodes the document back to the file, making its directory if missing
+func (file *varsFile) write() error {
+	var buffer bytes.Buffer
+	buffer.WriteString(file.header)
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(file.indent)
+	if err := encoder.Encode(file.doc); err != nil {
+		return &VarsFileError{Path: file.path, Err: err}
+	}
+	if err := encoder.Close(); err != nil {
+		return &VarsFileError{Path: file.path, Err: err}
+	}
+	if err := os.MkdirAll(filepath.Dir(file.path), 0o755); err != nil {
+		return &VarsFileError{Path: file.path, Err: err}
+	}
+	if err := writeFileAtomic(file.path, buffer.Bytes(), file.mode); err != nil {
+		return &VarsFileError{Path: file.path, Err: err}
+	}
+	return nil
+}
+
+// detectIndent returns the smallest indentation of nested lines, 2 if there is none
+func detectIndent(data []byte) int {
+	indent := 0
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		spaces := len(line) - len(trimmed)
+		if spaces == 0 || strings.TrimSpace(trimmed) == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if indent == 0 || spaces < indent {
+			indent = spaces
+		}
+	}
+	if indent < 2 {
+		return 2
+	}
+	return indent
+}
@@ -0,0 +1,162 @@
+package aini
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func readFileForTest(t *testing.T, path string) string {
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	return string(data)
+}
+
+func TestSetHostVar(t *testing.T) {
+	root := writeVarsFiles(t, map[string]string{
+		"hosts": "[web]\nhost1\nhost2\nhost3\n",
+		"host_vars/host1.yml": `---
+# managed by rotation
+serial: 1 # rotated daily
+ports:
+    - 80
+    - 443
+`,
+		"host_vars/host2/main.yml":  "a: 1\n",
+		"host_vars/host2/other.yml": "b: 2\n",
+		"group_vars/web.yml":        "serial: 0\n",
+	})
+	v, err := ParseFile(filepath.Join(root, "hosts"))
+	assert.Nil(t, err)
+	assert.Nil(t, v.AddVars(root))
+
+	path, err := v.SetHostVar(v.Hosts["host1"], "serial", 2, VarsWriteOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(root, "host_vars", "host1.yml"), path)
+	path, err = v.SetHostVar(v.Hosts["host1"], "owner", "ops", VarsWriteOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(root, "host_vars", "host1.yml"), path)
+	_, err = v.SetHostVar(v.Hosts["host1"], "tags", []string{"a", "b"}, VarsWriteOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, `---
+# managed by rotation
+serial: 2 # rotated daily
+ports:
+    - 80
+    - 443
+owner: ops
+tags:
+    - a
+    - b
+`, readFileForTest(t, path))
+	assert.Equal(t, "2", v.Hosts["host1"].Vars["serial"])
+	assert.Equal(t, `["a","b"]`, v.Hosts["host1"].Vars["tags"])
+
+	// existing definition in a directory of files, new variables in the last file
+	path, err = v.SetHostVar(v.Hosts["host2"], "a", "yes", VarsWriteOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(root, "host_vars", "host2", "main.yml"), path)
+	path, err = v.SetHostVar(v.Hosts["host2"], "c", 3, VarsWriteOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(root, "host_vars", "host2", "other.yml"), path)
+	assert.Equal(t, "b: 2\nc: 3\n", readFileForTest(t, path))
+
+	// new files
+	path, err = v.SetHostVar(v.Hosts["host3"], "serial", 5, VarsWriteOptions{DefaultFile: "{name}/generated.yml"})
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(root, "host_vars", "host3", "generated.yml"), path)
+	assert.Equal(t, "serial: 5\n", readFileForTest(t, path))
+	path, err = v.SetGroupVar(v.Groups["web"], "owner", "web-team", VarsWriteOptions{DefaultFile: "{name}.yaml"})
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(root, "group_vars", "web.yaml"), path)
+	assert.Equal(t, "web-team", v.Hosts["host3"].Vars["owner"])
+
+	_, err = v.SetHostVar(v.Hosts["host3"], "x", 1, VarsWriteOptions{DefaultFile: "{name}-x.yml"})
+	assert.NotNil(t, err)
+	_, err = v.SetHostVar(v.Hosts["host3"], "x", 1, VarsWriteOptions{DefaultFile: "{name}/x.json"})
+	assert.NotNil(t, err)
+
+	// the written files load to the same variables
+	reloaded, err := ParseFile(filepath.Join(root, "hosts"))
+	assert.Nil(t, err)
+	assert.Nil(t, reloaded.AddVarsStrict(root))
+	for name, host := range v.Hosts {
+		assert.Equal(t, host.Vars, reloaded.Hosts[name].Vars, name)
+	}
+	assert.Equal(t, "yes", reloaded.Hosts["host2"].Vars["a"])
+}
+
+func TestSetHostVarWithoutVars(t *testing.T) {
+	v := parseString(t, "host1")
+	_, err := v.SetHostVar(v.Hosts["host1"], "serial", 1, VarsWriteOptions{})
+	assert.NotNil(t, err)
+
+	root := t.TempDir()
+	path, err := v.SetHostVar(v.Hosts["host1"], "serial", 1, VarsWriteOptions{Root: root})
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(root, "host_vars", "host1.yml"), path)
+	assert.Equal(t, "1", v.Hosts["host1"].Vars["serial"])
+	assert.Equal(t, []string{filepath.Join(root, "group_vars"), filepath.Join(root, "host_vars")}, v.inputPaths())
+}
+
+func TestDeleteVar(t *testing.T) {
+	root := writeVarsFiles(t, map[string]string{
+		"hosts":                     "[web]\nhost1\nhost2\n",
+		"host_vars/host1.yml":       "# header\nkeep: 1\nserial: 1\n",
+		"host_vars/host1/extra.yml": "serial: 2\n",
+		"group_vars/web.yml":        "serial: 0\nteam: web\n",
+	})
+	v, err := ParseFile(filepath.Join(root, "hosts"))
+	assert.Nil(t, err)
+	assert.Nil(t, v.AddVars(root))
+	// the directory is walked before the file of the same name
+	assert.Equal(t, "1", v.Hosts["host1"].Vars["serial"])
+
+	paths, err := v.DeleteHostVar(v.Hosts["host1"], "serial")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{filepath.Join(root, "host_vars", "host1", "extra.yml"), filepath.Join(root, "host_vars", "host1.yml")}, paths)
+	assert.Equal(t, "{}\n", readFileForTest(t, paths[0]))
+	assert.Equal(t, "# header\nkeep: 1\n", readFileForTest(t, paths[1]))
+	assert.Equal(t, "0", v.Hosts["host1"].Vars["serial"])
+
+	paths, err = v.DeleteHostVar(v.Hosts["host1"], "serial")
+	assert.Nil(t, err)
+	assert.Empty(t, paths)
+
+	paths, err = v.DeleteGroupVar(v.Groups["web"], "serial")
+	assert.Nil(t, err)
+	assert.Len(t, paths, 1)
+	_, ok := v.Hosts["host2"].Vars["serial"]
+	assert.False(t, ok)
+	assert.Equal(t, "web", v.Hosts["host2"].Vars["team"])
+}
+
+func TestWriteVarsVaulted(t *testing.T) {
+	root := writeVarsFiles(t, map[string]string{
+		"hosts":               "host1\nhost2\n",
+		"host_vars/host1.yml": encryptVaultForTest("secret: value\n", "vaultpass"),
+		"host_vars/host2.yml": "plain: value\napi_key: !vault |\n    " +
+			strings.ReplaceAll(strings.TrimSpace(encryptVaultForTest("inline-secret", "vaultpass")), "\n", "\n    ") + "\n",
+		"host_vars/broken.yml": "key: [unclosed\n",
+	})
+	v, err := ParseFile(filepath.Join(root, "hosts"))
+	assert.Nil(t, err)
+	assert.Nil(t, v.doAddVars(root, varsOptions{vaultPasswords: []string{"vaultpass"}}))
+
+	_, err = v.SetHostVar(v.Hosts["host1"], "secret", "other", VarsWriteOptions{})
+	assert.NotNil(t, err)
+	_, err = v.DeleteHostVar(v.Hosts["host1"], "secret")
+	assert.NotNil(t, err)
+	_, err = v.SetHostVar(v.Hosts["host1"], "plain", "ok", VarsWriteOptions{})
+	assert.NotNil(t, err)
+
+	_, err = v.SetHostVar(v.Hosts["host2"], "api_key", "leaked", VarsWriteOptions{})
+	assert.Equal(t, filepath.Join(root, "host_vars", "host2.yml")+":2: variable 'api_key' is encrypted by vault", err.Error())
+	_, err = v.SetHostVar(v.Hosts["host2"], "plain", "changed", VarsWriteOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "changed", v.Hosts["host2"].Vars["plain"])
+	assert.Equal(t, "inline-secret", v.Hosts["host2"].Vars["api_key"])
+}
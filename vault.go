@@ -0,0 +1,118 @@
+package aini
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrVaultPassword is returned when none of the given vault passwords can decrypt vaulted data
+var ErrVaultPassword = errors.New("no vault password matches the vaulted data")
+
+const (
+	vaultHeader     = "$ANSIBLE_VAULT"
+	vaultIterations = 10000
+	vaultKeyLength  = 32
+)
+
+// isVaultData checks whether the data is encrypted by Ansible Vault
+func isVaultData(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte(vaultHeader+";"))
+}
+
+// decryptVault decrypts data encrypted by Ansible Vault (format 1.1 and 1.2, AES256), trying each password
+func decryptVault(data []byte, passwords []string) ([]byte, error) {
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	header := strings.Split(strings.TrimSpace(lines[0]), ";")
+	if len(header) < 3 || header[0] != vaultHeader {
+		return nil, fmt.Errorf("invalid vault header: %s", lines[0])
+	}
+	if header[1] != "1.1" && header[1] != "1.2" {
+		return nil, fmt.Errorf("unsupported vault format version: %s", header[1])
+	}
+	if header[2] != "AES256" {
+		return nil, fmt.Errorf("unsupported vault cipher: %s", header[2])
+	}
+
+	var body strings.Builder
+	for _, line := range lines[1:] {
+		body.WriteString(strings.TrimSpace(line))
+	}
+	envelope, err := hex.DecodeString(body.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid vault data: %w", err)
+	}
+	parts := strings.Split(string(envelope), "\n")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid vault data: expected salt, HMAC and ciphertext")
+	}
+	salt, err1 := hex.DecodeString(parts[0])
+	expectedMAC, err2 := hex.DecodeString(parts[1])
+	ciphertext, err3 := hex.DecodeString(parts[2])
+	if err := errors.Join(err1, err2, err3); err != nil {
+		return nil, fmt.Errorf("invalid vault data: %w", err)
+	}
+
+	for _, password := range passwords {
+		cipherKey, macKey, iv := deriveVaultKeys([]byte(password), salt)
+		mac := hmac.New(sha256.New, macKey)
+		mac.Write(ciphertext)
+		if !hmac.Equal(mac.Sum(nil), expectedMAC) {
+			continue
+		}
+		block, err := aes.NewCipher(cipherKey)
+		if err != nil {
+			return nil, err
+		}
+		plaintext := make([]byte, len(ciphertext))
+		cipher.NewCTR(block, iv).XORKeyStream(plaintext, ciphertext)
+		return unpadPKCS7(plaintext)
+	}
+	return nil, ErrVaultPassword
+}
+
+// deriveVaultKeys derives AES key, HMAC key and IV from the password as Ansible Vault does
+func deriveVaultKeys(password []byte, salt []byte) ([]byte, []byte, []byte) {
+	derived := pbkdf2.Key(password, salt, vaultIterations, 2*vaultKeyLength+aes.BlockSize, sha256.New)
+	return derived[:vaultKeyLength], derived[vaultKeyLength : 2*vaultKeyLength], derived[2*vaultKeyLength:]
+}
+
+func unpadPKCS7(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return data, nil
+	}
+	n := int(data[len(data)-1])
+	if n == 0 || n > aes.BlockSize || n > len(data) {
+		return nil, fmt.Errorf("invalid vault padding")
+	}
+	return data[:len(data)-n], nil
+}
+
+// decryptVaultNodes replaces values tagged with `!vault` in the YAML document with decrypted strings
+func decryptVaultNodes(node *yaml.Node, passwords []string) error {
+	if node.Tag == "!vault" && node.Kind == yaml.ScalarNode {
+		plaintext, err := decryptVault([]byte(node.Value), passwords)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", node.Line, err)
+		}
+		node.Tag = "!!str"
+		node.Value = string(plaintext)
+		node.Style = 0
+		return nil
+	}
+	for _, child := range node.Content {
+		if err := decryptVaultNodes(child, passwords); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package aini
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// encryptVaultForTest encrypts data in Ansible Vault 1.1 format
+func encryptVaultForTest(plaintext string, password string) string {
+	salt := []byte("0123456789abcdef0123456789abcdef")
+	cipherKey, macKey, iv := deriveVaultKeys([]byte(password), salt)
+	padding := aes.BlockSize - len(plaintext)%aes.BlockSize
+	padded := []byte(plaintext + strings.Repeat(string(rune(padding)), padding))
+	block, _ := aes.NewCipher(cipherKey)
+	ciphertext := make([]byte, len(padded))
+	cipher.NewCTR(block, iv).XORKeyStream(ciphertext, padded)
+	mac := hmac.New(sha256.New, macKey)
+	mac.Write(ciphertext)
+	envelope := hex.EncodeToString([]byte(fmt.Sprintf("%x\n%x\n%x", salt, mac.Sum(nil), ciphertext)))
+
+	var sb strings.Builder
+	sb.WriteString("$ANSIBLE_VAULT;1.1;AES256\n")
+	for len(envelope) > 80 {
+		sb.WriteString(envelope[:80] + "\n")
+		envelope = envelope[80:]
+	}
+	sb.WriteString(envelope + "\n")
+	return sb.String()
+}
+
+// TestDecryptVaultGolden decrypts fixtures made independently of this package by test_data/vault/generate.py
+func TestDecryptVaultGolden(t *testing.T) {
+	data, err := os.ReadFile("test_data/vault/group_vars/web.yml")
+	assert.Nil(t, err)
+	plaintext, err := decryptVault(data, []string{"wrong", "golden-pass"})
+	assert.Nil(t, err)
+	assert.Equal(t, "db_password: s3cret\n", string(plaintext))
+
+	_, err = decryptVault(data, []string{"wrong"})
+	assert.True(t, errors.Is(err, ErrVaultPassword))
+
+	v, err := Load("test_data/vault/inventory", WithVault("golden-pass"), WithStrict())
+	assert.Nil(t, err)
+	assert.Equal(t, "s3cret", v.HostVars("host1")["db_password"])
+	assert.Equal(t, "inline-secret", v.HostVars("host1")["api_key"])
+	assert.Equal(t, "value", v.HostVars("host1")["plain"])
+}
+
+func TestDecryptVault(t *testing.T) {
+	vaulted := encryptVaultForTest("secret: value\n", "pass2")
+
+	plaintext, err := decryptVault([]byte(vaulted), []string{"pass1", "pass2"})
+	assert.Nil(t, err)
+	assert.Equal(t, "secret: value\n", string(plaintext))
+
+	_, err = decryptVault([]byte(vaulted), []string{"pass1"})
+	assert.True(t, errors.Is(err, ErrVaultPassword))
+
+	_, err = decryptVault([]byte("$ANSIBLE_VAULT;2.0;AES256\n00"), []string{"pass1"})
+	assert.NotNil(t, err)
+}
+
+func TestAddVarsWithVault(t *testing.T) {
+	root := t.TempDir()
+	assert.Nil(t, os.MkdirAll(filepath.Join(root, "group_vars"), 0o755))
+	assert.Nil(t, os.MkdirAll(filepath.Join(root, "host_vars"), 0o755))
+	assert.Nil(t, os.WriteFile(filepath.Join(root, "group_vars", "web.yml"), []byte(encryptVaultForTest("db_password: s3cret\n", "vaultpass")), 0o644))
+
+	inline := "    " + strings.ReplaceAll(strings.TrimSpace(encryptVaultForTest("inline-secret", "vaultpass")), "\n", "\n    ")
+	assert.Nil(t, os.WriteFile(filepath.Join(root, "host_vars", "host1.yml"), []byte("plain: value\napi_key: !vault |\n"+inline+"\n"), 0o644))
+
+	v := parseString(t, `
+	[web]
+	host1
+	`)
+	assert.Nil(t, v.doAddVars(root, varsOptions{strict: true, vaultPasswords: []string{"vaultpass"}}))
+	assert.Equal(t, "s3cret", v.Hosts["host1"].Vars["db_password"])
+	assert.Equal(t, "inline-secret", v.Hosts["host1"].Vars["api_key"])
+	assert.Equal(t, "value", v.Hosts["host1"].Vars["plain"])
+
+	v = parseString(t, `
+	[web]
+	host1
+	`)
+	err := v.doAddVars(root, varsOptions{strict: true, vaultPasswords: []string{"wrong"}})
+	assert.True(t, errors.Is(err, ErrVaultPassword))
+}
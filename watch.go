@@ -0,0 +1,184 @@
+package aini
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// WatchOptions controls polling of Watcher. Zero values mean defaults.
+type WatchOptions struct {
+	// Interval is the time between checks of files, 2 seconds by default
+	Interval time.Duration
+	// Debounce is how long files must stay unchanged before reloading, to skip partial edits.
+	// 500ms by default, negative to reload at the first check seeing changes.
+	Debounce time.Duration
+}
+
+// WatchEvent is sent to subscribers of Watcher after reloading
+type WatchEvent struct {
+	// Inventory is the newly loaded inventory, or the previous one kept if Err is set
+	Inventory *Inventory
+	// Diff summarizes changes from the previous inventory, empty if Err is set
+	Diff InventoryDiff
+	// Err is the error of reloading, in which case the previous inventory is kept
+	Err error
+}
+
+// Watcher reloads an inventory by Load when any file it was loaded from changes: the inventory file and
+// all files in its group_vars and host_vars directories. Changes are detected by polling sizes and modification times.
+type Watcher struct {
+	path         string
+	loadOptions  []Option
+	watchOptions WatchOptions
+	manager      *InventoryManager
+
+	mutex       sync.Mutex
+	subscribers map[int]func(WatchEvent)
+	nextID      int
+	// inputs are the watched paths and state the state of them when the current inventory was loaded
+	inputs []string
+	state  string
+}
+
+// NewWatcher loads the inventory by Load for Watcher, failing if the initial loading fails.
+// Call Run to start watching.
+func NewWatcher(path string, watchOptions WatchOptions, options ...Option) (*Watcher, error) {
+	if watchOptions.Interval <= 0 {
+		watchOptions.Interval = 2 * time.Second
+	}
+	if watchOptions.Debounce < 0 {
+		watchOptions.Debounce = 0
+	} else if watchOptions.Debounce == 0 {
+		watchOptions.Debounce = 500 * time.Millisecond
+	}
+	watcher := &Watcher{
+		path:         path,
+		loadOptions:  options,
+		watchOptions: watchOptions,
+		subscribers:  make(map[int]func(WatchEvent)),
+	}
+	inventory, err := Load(path, options...)
+	if err != nil {
+		return nil, err
+	}
+	watcher.manager = NewInventoryManager(inventory)
+	watcher.inputs = inventory.data.inputPaths()
+	// an unknown state makes Run reload at the first check
+	watcher.state, _ = hashInputs(watcher.inputs)
+	return watcher, nil
+}
+
+// inputPaths returns the inventory file and group_vars and host_vars directories the inventory was loaded from
+func (inventory *InventoryData) inputPaths() []string {
+	paths := make([]string, 0, 1+2*len(inventory.varsRoots))
+	if inventory.path != "" && !IsHostList(inventory.path) {
+		paths = append(paths, inventory.path)
+	}
+	for _, root := range inventory.varsRoots {
+		paths = append(paths, filepath.Join(root.path, "group_vars"), filepath.Join(root.path, "host_vars"))
+	}
+	return paths
+}
+
+// hashInputs returns the current state of files in the paths
+func hashInputs(paths []string) (string, error) {
+	h := sha256.New()
+	for _, path := range paths {
+		if err := hashFileTree(h, path, false); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Current returns the last successfully loaded inventory
+func (watcher *Watcher) Current() *Inventory {
+	return watcher.manager.Current()
+}
+
+// Manager returns InventoryManager holding the inventory of the watcher
+func (watcher *Watcher) Manager() *InventoryManager {
+	return watcher.manager
+}
+
+// Subscribe registers a function to be called with every reloading, in the goroutine of Run.
+// The returned function cancels the subscription.
+func (watcher *Watcher) Subscribe(fn func(WatchEvent)) func() {
+	watcher.mutex.Lock()
+	defer watcher.mutex.Unlock()
+	id := watcher.nextID
+	watcher.nextID++
+	watcher.subscribers[id] = fn
+	return func() {
+		watcher.mutex.Lock()
+		defer watcher.mutex.Unlock()
+		delete(watcher.subscribers, id)
+	}
+}
+
+// Run polls the files until ctx is done, reloading the inventory after they change and stay unchanged for Debounce.
+// Failed reloading keeps the previous inventory and is retried only after files change again.
+func (watcher *Watcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(watcher.watchOptions.Interval)
+	defer ticker.Stop()
+	pendingState := ""
+	var pendingSince time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case now := <-ticker.C:
+			state, err := hashInputs(watcher.inputs)
+			if err != nil || state == watcher.state {
+				// files may be removed while being walked, to be checked again later
+				pendingState = ""
+				continue
+			}
+			if state != pendingState {
+				pendingState, pendingSince = state, now
+			}
+			if now.Sub(pendingSince) < watcher.watchOptions.Debounce {
+				continue
+			}
+			watcher.reload(ctx)
+			pendingState = ""
+		}
+	}
+}
+
+// reload loads the inventory, updates watched paths and notifies subscribers
+func (watcher *Watcher) reload(ctx context.Context) {
+	// the state is taken before loading, so that changes made during loading cause another reloading
+	state, _ := hashInputs(watcher.inputs)
+	previous := watcher.manager.Current()
+	inventory, err := watcher.manager.Reload(ctx, watcher.path, watcher.loadOptions...)
+	event := WatchEvent{Inventory: inventory, Err: err}
+	if err != nil {
+		event.Inventory = previous
+		watcher.state = state
+	} else {
+		event.Diff = previous.data.Diff(inventory.data)
+		inputs := inventory.data.inputPaths()
+		if !slices.Equal(inputs, watcher.inputs) {
+			state, _ = hashInputs(inputs)
+		}
+		watcher.inputs, watcher.state = inputs, state
+	}
+
+	watcher.mutex.Lock()
+	subscribers := make([]func(WatchEvent), 0, len(watcher.subscribers))
+	for id := 0; id < watcher.nextID; id++ {
+		if fn, ok := watcher.subscribers[id]; ok {
+			subscribers = append(subscribers, fn)
+		}
+	}
+	watcher.mutex.Unlock()
+	for _, fn := range subscribers {
+		fn(event)
+	}
+}
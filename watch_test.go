@@ -0,0 +1,93 @@
+package aini
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatcher(t *testing.T) {
+	root := writeVarsFiles(t, map[string]string{
+		"hosts":              "[web]\nweb1\nweb2\n",
+		"group_vars/web.yml": "env: prod\n",
+	})
+	watcher, err := NewWatcher(filepath.Join(root, "hosts"), WatchOptions{Interval: 5 * time.Millisecond, Debounce: -1}, WithStrict())
+	assert.Nil(t, err)
+	assert.Equal(t, "prod", watcher.Current().HostVars("web1")["env"])
+	assert.Equal(t, []string{filepath.Join(root, "hosts"), filepath.Join(root, "group_vars"), filepath.Join(root, "host_vars")},
+		watcher.inputs)
+
+	events := make(chan WatchEvent, 10)
+	unsubscribe := watcher.Subscribe(func(event WatchEvent) { events <- event })
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- watcher.Run(ctx) }()
+	nextEvent := func() WatchEvent {
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("no reloading")
+			return WatchEvent{}
+		}
+	}
+
+	// new host_vars directory is watched
+	assert.Nil(t, os.MkdirAll(filepath.Join(root, "host_vars"), 0o755))
+	assert.Nil(t, os.WriteFile(filepath.Join(root, "host_vars", "web2.yml"), []byte("env: dev\n"), 0o644))
+	event := nextEvent()
+	assert.Nil(t, event.Err)
+	assert.Equal(t, []string{"web2"}, event.Diff.ChangedHosts)
+	assert.Same(t, watcher.Current(), event.Inventory)
+	assert.Equal(t, "dev", watcher.Current().HostVars("web2")["env"])
+
+	// failed reloading keeps the last good inventory
+	good := watcher.Current()
+	assert.Nil(t, os.WriteFile(filepath.Join(root, "group_vars", "web.yml"), []byte("env: [unclosed\n"), 0o644))
+	event = nextEvent()
+	assert.NotNil(t, event.Err)
+	assert.Same(t, good, event.Inventory)
+	assert.Same(t, good, watcher.Current())
+
+	assert.Nil(t, os.WriteFile(filepath.Join(root, "hosts"), []byte("[web]\nweb1\nweb2\nweb3\n"), 0o644))
+	assert.Nil(t, os.WriteFile(filepath.Join(root, "group_vars", "web.yml"), []byte("env: staging\n"), 0o644))
+	event = nextEvent()
+	for event.Err != nil {
+		// the two files may be seen changed at different checks
+		event = nextEvent()
+	}
+	assert.Equal(t, []string{"web3"}, event.Diff.AddedHosts)
+	assert.Equal(t, "staging", watcher.Current().HostVars("web3")["env"])
+
+	unsubscribe()
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestWatcherDebounce(t *testing.T) {
+	root := writeVarsFiles(t, map[string]string{"hosts": "web1\n"})
+	watcher, err := NewWatcher(filepath.Join(root, "hosts"), WatchOptions{Interval: 5 * time.Millisecond, Debounce: 100 * time.Millisecond})
+	assert.Nil(t, err)
+	reloaded := make(chan WatchEvent, 10)
+	watcher.Subscribe(func(event WatchEvent) { reloaded <- event })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watcher.Run(ctx)
+
+	changed := time.Now()
+	assert.Nil(t, os.WriteFile(filepath.Join(root, "hosts"), []byte("web1\nweb2\n"), 0o644))
+	select {
+	case event := <-reloaded:
+		assert.GreaterOrEqual(t, time.Since(changed), 100*time.Millisecond)
+		assert.Equal(t, []string{"web2"}, event.Diff.AddedHosts)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reloading")
+	}
+
+	_, err = NewWatcher(filepath.Join(root, "nosuchfile"), WatchOptions{})
+	assert.NotNil(t, err)
+}